@@ -28,9 +28,15 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Filter by package type (npm, python, go, maven)",
+                        "description": "Filter by package type (npm, python, go, maven, cargo, nuget, composer, gem, github-actions, terraform, helm, docker, git-submodule, vendored, deb, apk, rpm)",
                         "name": "type",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by scope some report found the dependency in (runtime, optional, build, dev, test)",
+                        "name": "scope",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -727,6 +733,13 @@ const docTemplate = `{
                     "description": "SBOM data",
                     "type": "string"
                 },
+                "subprojects": {
+                    "description": "Where in the project each dependency was found (monorepo subprojects)",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/database.SubprojectDependency"
+                    }
+                },
                 "total_dependencies": {
                     "description": "Stats",
                     "type": "integer"
@@ -738,6 +751,28 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "database.SubprojectDependency": {
+            "type": "object",
+            "properties": {
+                "dependency_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "report_id": {
+                    "type": "integer"
+                },
+                "scope": {
+                    "description": "runtime, optional, build, dev or test",
+                    "type": "string"
+                },
+                "subproject": {
+                    "description": "path relative to the repository root, \"\" for the root project",
+                    "type": "string"
+                }
+            }
         }
     }
 }`
@@ -70,8 +70,7 @@ func GenerateReportForRepo(repoURL, projectName, projectDesc string, cfg *config
 	graphData, _ := os.ReadFile(graphPath)
 
 	// Calculate total dependencies and vulnerabilities
-	totalDeps := len(rep.Dependencies.GoModules) + len(rep.Dependencies.NpmPackages) +
-		len(rep.Dependencies.PythonReqs) + len(rep.Dependencies.MavenDeps)
+	totalDeps := rep.Dependencies.Packages.Len()
 
 	totalVulns := 0
 	for _, vulns := range cfg.VulnMap {
@@ -93,36 +92,16 @@ func GenerateReportForRepo(repoURL, projectName, projectDesc string, cfg *config
 
 	// Store dependencies (deduplicated)
 	dependencies := make([]*database.Dependency, 0)
-
-	// Process Go modules
-	for _, goMod := range rep.Dependencies.GoModules {
-		dep, err := database.GetOrCreateDependency("go", goMod.Path, goMod.Version)
-		if err == nil {
-			dependencies = append(dependencies, dep)
-		}
-	}
-
-	// Process NPM packages
-	for _, pkg := range rep.Dependencies.NpmPackages {
-		dep, err := database.GetOrCreateDependency("npm", pkg.Name, pkg.Version)
-		if err == nil {
-			dependencies = append(dependencies, dep)
-		}
-	}
-
-	// Process Python packages
-	for _, pkg := range rep.Dependencies.PythonReqs {
-		dep, err := database.GetOrCreateDependency("python", pkg.Name, pkg.Version)
-		if err == nil {
-			dependencies = append(dependencies, dep)
-		}
-	}
-
-	// Process Maven dependencies
-	for _, pkg := range rep.Dependencies.MavenDeps {
-		dep, err := database.GetOrCreateDependency("maven", pkg.Name, pkg.Version)
-		if err == nil {
-			dependencies = append(dependencies, dep)
+	var subprojects []database.SubprojectDependency
+
+	// Process packages of the registered ecosystems
+	for _, e := range rep.Dependencies.Packages {
+		for _, pkg := range e.Packages {
+			dep, err := database.GetOrCreateDependency(e.Ecosystem.ID, pkg.Name, pkg.Version)
+			if err == nil {
+				dependencies = append(dependencies, dep)
+				subprojects = append(subprojects, database.SubprojectDependency{DependencyID: dep.ID, Subproject: pkg.Subproject, Scope: pkg.Scope})
+			}
 		}
 	}
 
@@ -130,6 +109,7 @@ func GenerateReportForRepo(repoURL, projectName, projectDesc string, cfg *config
 	for i, dep := range dependencies {
 		dbReport.Dependencies[i] = *dep
 	}
+	dbReport.Subprojects = dedupeSubprojects(subprojects)
 
 	// Save report to database
 	if err := database.CreateReport(dbReport); err != nil {
@@ -146,23 +126,17 @@ func generateReport(cfg *config.Config) (*report.Report, error) {
 		BaseDir:     cfg.BaseDir,
 	}
 
-	// Run trivy SBOM
+	// Run trivy SBOM; a native one is written once the dependencies have been discovered
 	sbomPath := filepath.Join(cfg.OutDir, cfg.TrivySBOMName)
-	rep.Trivy = sbom.RunTrivy(cfg.TrivyPath, cfg.TrivyFormat, cfg.BaseDir, sbomPath)
+	if cfg.SBOMSource != "native" {
+		rep.Trivy = sbom.RunTrivy(cfg.TrivyPath, cfg.TrivyFormat, cfg.BaseDir, sbomPath)
+	}
 
-	// Parse SBOM (best-effort)
+	// Extract packages from SBOM components (best-effort)
 	if rep.Trivy.OK {
-		summary, err := sbom.ParseCycloneDX(sbomPath)
-		if err != nil {
-			rep.SBOM.Errors = append(rep.SBOM.Errors, err.Error())
-		} else {
-			rep.SBOM = *summary
-		}
-
-		// Extract packages from SBOM components
 		npmPkgs, pythonPkgs := sbom.ExtractPackagesFromSBOM(sbomPath)
-		rep.Dependencies.NpmPackages = append(rep.Dependencies.NpmPackages, npmPkgs...)
-		rep.Dependencies.PythonReqs = append(rep.Dependencies.PythonReqs, pythonPkgs...)
+		rep.Dependencies.Packages.Add(npmPkgs...)
+		rep.Dependencies.Packages.Add(pythonPkgs...)
 	}
 
 	// Run vulnerability scan
@@ -201,25 +175,32 @@ func generateReport(cfg *config.Config) (*report.Report, error) {
 	}
 
 	// Discover package repository usage (best-effort)
-	rep.Dependencies.GoModules = deps.DiscoverGoModules(cfg.BaseDir)
-	rep.Dependencies.NpmPackages = append(rep.Dependencies.NpmPackages, deps.DiscoverNpm(cfg.BaseDir)...)
-	rep.Dependencies.PythonReqs = append(rep.Dependencies.PythonReqs, deps.DiscoverPythonReqs(cfg.BaseDir)...)
-	rep.Dependencies.MavenDeps = deps.DiscoverMaven(cfg.BaseDir)
+	discoverOpts := deps.Options{MavenRepo: cfg.MavenRepo, Include: cfg.Include, Exclude: cfg.Exclude}
+	rep.Dependencies.Packages.Discover(cfg.BaseDir, discoverOpts)
+	rep.Dependencies.Packages.Merge()
+	rep.Dependencies.Packages = rep.Dependencies.Packages.FilterScopes(cfg.Scopes)
+	if cfg.SBOMSource != "trivy" {
+		// Complete Trivy's SBOM with the discovered dependencies, or stand in for it
+		rep.Trivy = sbom.WriteProjectBOM(sbomPath, cfg.BaseDir, rep.Dependencies.Packages, rep.Trivy)
+	}
+	if rep.Trivy.OK {
+		// Parse SBOM (best-effort)
+		if summary, err := sbom.ParseCycloneDX(sbomPath); err != nil {
+			rep.SBOM.Errors = append(rep.SBOM.Errors, err.Error())
+		} else {
+			rep.SBOM = *summary
+		}
+	}
 
 	// Assess remote repos
 	rep.Repos = repo.AssessRemotes(cfg, rep.Project.Remotes, rep.Project.LastCommit)
 
-	// Extract repository info from Go modules
-	goModuleRepos := repo.ExtractReposFromGoModules(rep.Dependencies.GoModules)
-	rep.Repos = append(rep.Repos, repo.AssessModuleRepos(cfg, goModuleRepos)...)
-
-	// Extract repository info from Python packages
-	pythonRepos := repo.ExtractReposFromPythonPackages(cfg, rep.Dependencies.PythonReqs)
-	rep.Repos = append(rep.Repos, repo.AssessModuleRepos(cfg, pythonRepos)...)
-
-	// Extract repository info from NPM packages
-	npmRepos := repo.ExtractReposFromNpmPackages(cfg, rep.Dependencies.NpmPackages)
-	rep.Repos = append(rep.Repos, repo.AssessModuleRepos(cfg, npmRepos)...)
+	// Extract repository info from packages of ecosystems with a registry lookup
+	for _, e := range rep.Dependencies.Packages {
+		if remotes, ok := repo.ExtractReposFromPackages(cfg, e.Ecosystem.ID, e.Packages); ok {
+			rep.Repos = append(rep.Repos, repo.AssessModuleRepos(cfg, remotes)...)
+		}
+	}
 
 	// Generate dependency graph SVG
 	graphPath := filepath.Join(cfg.OutDir, cfg.GraphSVGName)
@@ -227,10 +208,7 @@ func generateReport(cfg *config.Config) (*report.Report, error) {
 	if err := graph.GenerateDependencyGraph(
 		graphPath,
 		projectName,
-		rep.Dependencies.GoModules,
-		rep.Dependencies.NpmPackages,
-		rep.Dependencies.PythonReqs,
-		rep.Dependencies.MavenDeps,
+		rep.Dependencies.Packages,
 		rep.Repos,
 	); err != nil {
 		fmt.Printf("Warning: failed to generate dependency graph: %v\n", err)
@@ -244,3 +222,17 @@ func generateReport(cfg *config.Config) (*report.Report, error) {
 
 	return rep, nil
 }
+
+// dedupeSubprojects drops repeated dependency/subproject pairs, e.g. a package
+// that is both in the SBOM and a lockfile of the same subproject.
+func dedupeSubprojects(in []database.SubprojectDependency) []database.SubprojectDependency {
+	seen := make(map[database.SubprojectDependency]bool)
+	var out []database.SubprojectDependency
+	for _, s := range in {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
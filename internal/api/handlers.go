@@ -465,17 +465,21 @@ func (h *Handler) GetDependencyStats(c *gin.Context) {
 // @Description Returns all unique dependencies across all projects (deduplicated)
 // @Tags dependencies
 // @Produce json
-// @Param type query string false "Filter by package type (npm, python, go, maven)"
+// @Param type query string false "Filter by package type (npm, python, go, maven, cargo, nuget, composer, gem, github-actions, terraform, helm, docker, git-submodule, vendored, deb, apk, rpm)"
+// @Param scope query string false "Filter by scope some report found the dependency in (runtime, optional, build, dev, test)"
 // @Success 200 {array} database.Dependency
 // @Failure 500 {object} ErrorResponse
 // @Router /api/v1/dependencies [get]
 func (h *Handler) ListDependencies(c *gin.Context) {
 	pkgType := c.Query("type")
+	scope := c.Query("scope")
 
 	var deps []database.Dependency
 	var err error
 
-	if pkgType != "" {
+	if scope != "" {
+		deps, err = database.GetDependenciesByScope(pkgType, scope)
+	} else if pkgType != "" {
 		deps, err = database.GetDependenciesByPackageType(pkgType)
 	} else {
 		deps, err = database.GetAllDependencies()
@@ -8,6 +8,7 @@ import (
 	_ "sbom-report/docs" // Import swagger docs
 	"sbom-report/internal/config"
 	"sbom-report/internal/database"
+	"sbom-report/internal/deps"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -52,6 +53,7 @@ func NewServer(dbPath string) (*Server, error) {
 		TrivySBOMName:  "sbom.cdx.json",
 		HTMLReportName: "report.html",
 		GraphSVGName:   "dependency-graph.svg",
+		MavenRepo:      deps.DefaultMavenRepo(),
 		GitHubToken:    os.Getenv("GITHUB_TOKEN"),
 		UserAgent:      "sbom-report-api/1.0",
 		RequestTimeout: 30 * time.Second,
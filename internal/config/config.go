@@ -16,6 +16,8 @@ type VulnInfo struct {
 
 type Config struct {
 	BaseDir        string
+	BinaryPath     string // Go executable, or directory of them, scanned instead of BaseDir
+	ImagePath      string // container image tarball or OCI layout scanned instead of BaseDir
 	OutDir         string
 	TrivyPath      string
 	GitHubToken    string
@@ -28,5 +30,10 @@ type Config struct {
 	TrivySBOMName  string
 	HTMLReportName string
 	GraphSVGName   string
+	MavenRepo      string
+	Include        []string // directory globs manifest discovery is limited to
+	Exclude        []string // directory globs manifest discovery skips
+	Scopes         []string // dependency scopes reported (deps.Scopes); all when empty
+	SBOMSource     string   // builder of a source tree's SBOM: "trivy", "native" or "merged" ("" is merged)
 	VulnMap        map[string][]VulnInfo
 }
@@ -22,7 +22,7 @@ func InitDB(dbPath string) error {
 	}
 
 	// Auto migrate the schema
-	if err := DB.AutoMigrate(&Project{}, &Report{}, &Dependency{}); err != nil {
+	if err := DB.AutoMigrate(&Project{}, &Report{}, &Dependency{}, &SubprojectDependency{}); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
@@ -121,7 +121,7 @@ func CreateReport(report *Report) error {
 // GetReport retrieves a report by ID
 func GetReport(id uint) (*Report, error) {
 	var report Report
-	if err := DB.Preload("Project").Preload("Dependencies").First(&report, id).Error; err != nil {
+	if err := DB.Preload("Project").Preload("Dependencies").Preload("Subprojects").First(&report, id).Error; err != nil {
 		return nil, err
 	}
 	return &report, nil
@@ -187,3 +187,17 @@ func GetDependenciesByPackageType(pkgType string) ([]Dependency, error) {
 	}
 	return deps, nil
 }
+
+// GetDependenciesByScope returns dependencies some report found in the
+// given scope, optionally also filtered by package type
+func GetDependenciesByScope(pkgType, scope string) ([]Dependency, error) {
+	var deps []Dependency
+	query := DB.Where("id IN (?)", DB.Model(&SubprojectDependency{}).Select("dependency_id").Where("scope = ?", scope))
+	if pkgType != "" {
+		query = query.Where("package_type = ?", pkgType)
+	}
+	if err := query.Find(&deps).Error; err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
@@ -48,6 +48,21 @@ type Report struct {
 	TotalVulns        int `json:"total_vulns"`
 
 	Dependencies []Dependency `gorm:"many2many:report_dependencies;" json:"dependencies,omitempty"`
+
+	// Where in the project each dependency was found (monorepo subprojects)
+	Subprojects []SubprojectDependency `gorm:"foreignKey:ReportID" json:"subprojects,omitempty"`
+}
+
+// SubprojectDependency records that a report found a dependency in a given
+// subproject and scope, so a monorepo's dependencies can be grouped by
+// subproject and filtered by what they are needed for
+type SubprojectDependency struct {
+	ID uint `gorm:"primarykey" json:"id"`
+
+	ReportID     uint   `gorm:"not null;index" json:"report_id"`
+	DependencyID uint   `gorm:"not null;index" json:"dependency_id"`
+	Subproject   string `gorm:"index" json:"subproject"` // path relative to the repository root, "" for the root project
+	Scope        string `gorm:"index" json:"scope"`      // runtime, optional, build, dev or test
 }
 
 // Dependency represents a unique dependency across all projects
@@ -59,7 +74,7 @@ type Dependency struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Unique combination of package type, name, and version
-	PackageType string `gorm:"not null;index:idx_dependency_unique" json:"package_type"` // npm, python, go, maven
+	PackageType string `gorm:"not null;index:idx_dependency_unique" json:"package_type"` // npm, python, go, maven, cargo, nuget, composer, gem, github-actions, terraform, helm, docker, git-submodule, vendored, deb, apk, rpm
 	Name        string `gorm:"not null;index:idx_dependency_unique" json:"name"`
 	Version     string `gorm:"not null;index:idx_dependency_unique" json:"version"`
 
@@ -85,3 +100,7 @@ func (Report) TableName() string {
 func (Dependency) TableName() string {
 	return "dependencies"
 }
+
+func (SubprojectDependency) TableName() string {
+	return "subproject_dependencies"
+}
@@ -0,0 +1,151 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+type actionStep struct {
+	Uses string `yaml:"uses"`
+}
+
+// actionFile covers both workflow files (jobs) and action metadata (runs).
+type actionFile struct {
+	Jobs map[string]struct {
+		Uses  string       `yaml:"uses"` // reusable workflow
+		Steps []actionStep `yaml:"steps"`
+	} `yaml:"jobs"`
+	Runs struct {
+		Using string       `yaml:"using"`
+		Image string       `yaml:"image"` // docker actions
+		Steps []actionStep `yaml:"steps"` // composite actions
+	} `yaml:"runs"`
+}
+
+var commitSHARe = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// DiscoverGitHubActions lists the "uses:" references of the workflows in
+// dir/.github/workflows, the composite and docker actions kept in
+// dir/.github/actions and an action.yml in dir itself: actions
+// ("owner/repo[/path]@ref"), reusable workflows and docker:// images.
+// Local references ("./...") are skipped. References not pinned to a full
+// commit SHA (or an image digest) have Unpinned set.
+func DiscoverGitHubActions(dir string) []PackageRef {
+	var files []string
+	for _, pattern := range []string{
+		".github/workflows/*.yml", ".github/workflows/*.yaml",
+		".github/actions/*/action.yml", ".github/actions/*/action.yaml",
+		".github/actions/*/*/action.yml", ".github/actions/*/*/action.yaml",
+		"action.yml", "action.yaml",
+	} {
+		matches, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+		files = append(files, matches...)
+	}
+
+	var refs []PackageRef
+	for _, path := range files {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var doc actionFile
+		if yaml.Unmarshal(b, &doc) != nil {
+			continue
+		}
+		source := relSource(dir, path)
+		add := func(uses, job string) {
+			if ref, ok := parseActionUses(uses); ok {
+				ref.Source = source
+				ref.Direct = true
+				if job != "" {
+					ref.Groups = []string{job}
+				}
+				refs = append(refs, ref)
+			}
+		}
+
+		jobs := make([]string, 0, len(doc.Jobs))
+		for id := range doc.Jobs {
+			jobs = append(jobs, id)
+		}
+		sort.Strings(jobs)
+		for _, id := range jobs {
+			job := doc.Jobs[id]
+			add(job.Uses, id)
+			for _, s := range job.Steps {
+				add(s.Uses, id)
+			}
+		}
+		for _, s := range doc.Runs.Steps {
+			add(s.Uses, "")
+		}
+		if doc.Runs.Using == "docker" {
+			add(doc.Runs.Image, "")
+		}
+	}
+	return refs
+}
+
+// parseActionUses parses one "uses:" value.
+func parseActionUses(uses string) (PackageRef, bool) {
+	uses = strings.TrimSpace(uses)
+	if uses == "" || strings.HasPrefix(uses, "./") || uses == "Dockerfile" || strings.Contains(uses, "${{") {
+		return PackageRef{}, false
+	}
+	ref := PackageRef{Ecosystem: "github-actions"}
+
+	if image, ok := strings.CutPrefix(uses, "docker://"); ok {
+		ref.RefType = "docker"
+		ref.Name, ref.Version = splitImageRef(image)
+		if !strings.HasPrefix(ref.Version, "sha256:") {
+			ref.Unpinned = "image tag, not a digest"
+		}
+		return ref, true
+	}
+
+	name, version, ok := strings.Cut(uses, "@")
+	if !ok || strings.Count(name, "/") < 1 {
+		return PackageRef{}, false
+	}
+	ref.Name, ref.Version = name, version
+	ref.RefType = "action"
+	if strings.Contains(name, "/.github/workflows/") {
+		ref.RefType = "reusable workflow"
+	}
+	if !commitSHARe.MatchString(version) {
+		ref.Unpinned = "ref " + version + " is not a commit SHA"
+	}
+	return ref, true
+}
+
+// splitImageRef splits "registry/name:tag" or "name[:tag]@sha256:..." into
+// name and tag or digest; a missing tag means "latest".
+func splitImageRef(image string) (name, version string) {
+	name, digest, pinned := strings.Cut(image, "@")
+	version = "latest"
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, version = name[:i], name[i+1:]
+	}
+	if pinned {
+		version = digest
+	}
+	return name, version
+}
+
+// GitHubActionRepo returns the "owner/repo" an action or reusable workflow
+// reference lives in, or "" for docker images.
+func GitHubActionRepo(p PackageRef) string {
+	if p.Ecosystem != "github-actions" || p.RefType == "docker" {
+		return ""
+	}
+	parts := strings.SplitN(p.Name, "/", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0] + "/" + parts[1]
+}
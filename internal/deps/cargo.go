@@ -0,0 +1,340 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+type cargoDepTables struct {
+	Dependencies      map[string]any `toml:"dependencies"`
+	DevDependencies   map[string]any `toml:"dev-dependencies"`
+	BuildDependencies map[string]any `toml:"build-dependencies"`
+}
+
+type cargoManifest struct {
+	cargoDepTables
+	Package struct {
+		Name string `toml:"name"`
+	} `toml:"package"`
+	Workspace struct {
+		Members      []string       `toml:"members"`
+		Exclude      []string       `toml:"exclude"`
+		Dependencies map[string]any `toml:"dependencies"`
+	} `toml:"workspace"`
+	Target map[string]cargoDepTables `toml:"target"` // [target.'cfg(unix)'.dependencies]
+}
+
+type cargoLock struct {
+	Package []struct {
+		Name         string   `toml:"name"`
+		Version      string   `toml:"version"`
+		Source       string   `toml:"source"`
+		Checksum     string   `toml:"checksum"`
+		Dependencies []string `toml:"dependencies"`
+	} `toml:"package"`
+}
+
+// cargoMember is a workspace member (or the single root package) together
+// with the dependencies its manifest declares, keyed by crate name.
+type cargoMember struct {
+	name     string
+	path     string // relative to the workspace root, "" for the root package
+	declared map[string]cargoDeclared
+}
+
+type cargoDeclared struct {
+	name     string // crate name; differs from the table key when renamed
+	spec     string
+	kind     string // "normal", "dev" or "build"
+	source   string // "", "vcs" or "path"
+	url      string
+	optional bool
+}
+
+// DiscoverCargo reads Cargo.lock, using Cargo.toml (and the manifests of
+// workspace members) to classify direct, dev and build dependencies. Each
+// member's dependency closure is attributed through PackageRef.Subproject.
+// Without a lockfile the declared dependencies are reported as written.
+func DiscoverCargo(dir string) []PackageRef {
+	root, ok := readCargoManifest(filepath.Join(dir, "Cargo.toml"))
+	if !ok {
+		return nil
+	}
+	members := cargoMembers(dir, root)
+
+	b, err := os.ReadFile(filepath.Join(dir, "Cargo.lock"))
+	var lock cargoLock
+	if err != nil || toml.Unmarshal(b, &lock) != nil {
+		return cargoDeclaredRefs(members)
+	}
+
+	isMember := make(map[string]bool)
+	for _, m := range members {
+		isMember[m.name] = true
+	}
+
+	g := newLockGraph()
+	memberKeys := make(map[string]string)
+	for _, p := range lock.Package {
+		n := &lockNode{ref: PackageRef{
+			Ecosystem: "cargo",
+			Name:      p.Name,
+			Version:   p.Version,
+			Source:    "Cargo.lock",
+			Integrity: p.Checksum,
+		}}
+		switch {
+		case strings.HasPrefix(p.Source, "git+"):
+			n.ref.Kind = "vcs"
+		case p.Source == "":
+			n.ref.Kind = "path"
+			if isMember[p.Name] {
+				memberKeys[p.Name] = p.Name + " " + p.Version
+			}
+		}
+		for _, d := range p.Dependencies {
+			n.deps = append(n.deps, lockEdge{key: cargoDepKey(d)})
+		}
+		keys := []string{p.Name + " " + p.Version}
+		if _, dup := g.nodes[p.Name]; !dup {
+			keys = append(keys, p.Name)
+		}
+		g.add(keys, n)
+	}
+
+	// the lockfile lists dev-dependencies of workspace crates alongside their
+	// other dependencies; crates depending on a member do not pull those in
+	memberDeps := make(map[string][]lockEdge)
+	for _, m := range members {
+		self := g.nodes[memberKeys[m.name]]
+		if self == nil {
+			continue
+		}
+		memberDeps[m.name] = self.deps
+		var kept []lockEdge
+		for _, e := range self.deps {
+			if child := g.nodes[e.key]; child != nil && m.declared[child.ref.Name].kind == "dev" {
+				continue
+			}
+			kept = append(kept, e)
+		}
+		self.deps = kept
+	}
+
+	var refs []PackageRef
+	for _, m := range members {
+		var normal, dev, build, required []string
+		for _, e := range memberDeps[m.name] {
+			child := g.nodes[e.key]
+			if child == nil {
+				continue
+			}
+			d, ok := m.declared[child.ref.Name]
+			if !ok || !d.optional {
+				required = append(required, e.key)
+			}
+			switch {
+			case ok && d.kind == "dev":
+				dev = append(dev, e.key)
+			case ok && d.kind == "build":
+				build = append(build, e.key)
+			default:
+				normal = append(normal, e.key)
+			}
+		}
+
+		sub := g.subgraph(append(append(append([]string{}, normal...), dev...), build...))
+		viaNormal := sub.reachable(normal)
+		viaBuild := sub.reachable(build)
+		viaRequired := sub.reachable(required)
+		for _, n := range sub.nodes {
+			// only pulled in through optional (feature-gated) dependencies
+			n.ref.Optional = !viaRequired[n]
+			switch {
+			case viaNormal[n]:
+				n.ref.NativeScope = "normal"
+			case viaBuild[n]:
+				n.ref.NativeScope = "build"
+			default:
+				n.ref.NativeScope = "dev"
+			}
+		}
+		// build-dependencies are compiled for every build, so only what
+		// nothing but dev-dependencies reaches is Dev
+		for _, r := range sub.refs(append(append([]string{}, normal...), build...), dev) {
+			if isMember[r.Name] && r.Kind == "path" {
+				continue // another crate of the same workspace
+			}
+			r.Subproject = m.path
+			refs = append(refs, r)
+		}
+	}
+	sort.SliceStable(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	return refs
+}
+
+// cargoDepKey turns a Cargo.lock dependency entry ("name", "name version" or
+// "name version (source)") into a graph key.
+func cargoDepKey(entry string) string {
+	fields := strings.Fields(entry)
+	if len(fields) >= 2 {
+		return fields[0] + " " + fields[1]
+	}
+	return entry
+}
+
+// cargoMemberDirs returns the directories of the workspace members, which
+// DiscoverCargo reports itself.
+func cargoMemberDirs(dir string) []string {
+	root, ok := readCargoManifest(filepath.Join(dir, "Cargo.toml"))
+	if !ok {
+		return nil
+	}
+	var dirs []string
+	for _, m := range cargoMembers(dir, root) {
+		if m.path != "" {
+			dirs = append(dirs, filepath.Join(dir, filepath.FromSlash(m.path)))
+		}
+	}
+	return dirs
+}
+
+// cargoMembers returns the root package (if the manifest has one) and the
+// workspace members matched by [workspace] members/exclude globs.
+func cargoMembers(dir string, root cargoManifest) []cargoMember {
+	var members []cargoMember
+	if root.Package.Name != "" {
+		members = append(members, cargoMember{name: root.Package.Name, declared: root.declared(root)})
+	}
+
+	excluded := make(map[string]bool)
+	for _, pattern := range root.Workspace.Exclude {
+		matches, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+		for _, m := range matches {
+			excluded[filepath.Clean(m)] = true
+		}
+	}
+	for _, pattern := range root.Workspace.Members {
+		matches, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+		sort.Strings(matches)
+		for _, mdir := range matches {
+			if excluded[filepath.Clean(mdir)] {
+				continue
+			}
+			mf, ok := readCargoManifest(filepath.Join(mdir, "Cargo.toml"))
+			if !ok || mf.Package.Name == "" {
+				continue
+			}
+			members = append(members, cargoMember{
+				name:     mf.Package.Name,
+				path:     relSource(dir, mdir),
+				declared: mf.declared(root),
+			})
+		}
+	}
+	return members
+}
+
+func readCargoManifest(path string) (cargoManifest, bool) {
+	var mf cargoManifest
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return mf, false
+	}
+	return mf, toml.Unmarshal(b, &mf) == nil
+}
+
+// declared collects the manifest's dependency tables, including
+// target-specific ones. workspace = true entries take their specification
+// from the workspace root.
+func (mf cargoManifest) declared(root cargoManifest) map[string]cargoDeclared {
+	out := make(map[string]cargoDeclared)
+	tables := []cargoDepTables{mf.cargoDepTables}
+	for _, target := range sortedKeys(mf.Target) {
+		tables = append(tables, mf.Target[target])
+	}
+	rank := make(map[string]int)
+	for _, t := range tables {
+		// in order of precedence: a crate needed at runtime stays "normal"
+		// even if tests list it too, and one the build script needs is
+		// "build" rather than "dev"
+		for r, kt := range []struct {
+			kind  string
+			table map[string]any
+		}{
+			{"normal", t.Dependencies},
+			{"build", t.BuildDependencies},
+			{"dev", t.DevDependencies},
+		} {
+			for key, spec := range kt.table {
+				d := cargoDeclaration(key, spec, root)
+				d.kind = kt.kind
+				if prev, ok := rank[d.name]; ok && prev <= r {
+					continue
+				}
+				out[d.name], rank[d.name] = d, r
+			}
+		}
+	}
+	return out
+}
+
+func cargoDeclaration(key string, spec any, root cargoManifest) cargoDeclared {
+	d := cargoDeclared{name: key}
+	switch v := spec.(type) {
+	case string:
+		d.spec = v
+	case map[string]any:
+		if ws, _ := v["workspace"].(bool); ws {
+			if inherited, ok := root.Workspace.Dependencies[key]; ok {
+				d = cargoDeclaration(key, inherited, root)
+			}
+		}
+		if pkg, ok := v["package"].(string); ok {
+			d.name = pkg
+		}
+		if s, ok := v["version"].(string); ok {
+			d.spec = s
+		}
+		if git, ok := v["git"].(string); ok {
+			d.source, d.url = "vcs", git
+		} else if p, ok := v["path"].(string); ok {
+			d.source, d.url = "path", p
+		}
+		d.optional, _ = v["optional"].(bool)
+	}
+	return d
+}
+
+func cargoDeclaredRefs(members []cargoMember) []PackageRef {
+	isMember := make(map[string]bool)
+	for _, m := range members {
+		isMember[m.name] = true
+	}
+	var refs []PackageRef
+	for _, m := range members {
+		for _, name := range sortedKeys(m.declared) {
+			d := m.declared[name]
+			if isMember[d.name] {
+				continue
+			}
+			refs = append(refs, PackageRef{
+				Ecosystem:   "cargo",
+				Name:        d.name,
+				Version:     d.spec,
+				Source:      filepath.ToSlash(filepath.Join(m.path, "Cargo.toml")),
+				Direct:      true,
+				Dev:         d.kind == "dev",
+				Optional:    d.optional,
+				Kind:        d.source,
+				NativeScope: d.kind,
+				Subproject:  m.path,
+			})
+		}
+	}
+	return refs
+}
@@ -0,0 +1,100 @@
+package deps
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type composerLockPackage struct {
+	Name    string            `json:"name"`
+	Version string            `json:"version"`
+	Require map[string]string `json:"require"`
+	Dist    struct {
+		Type   string `json:"type"`
+		Shasum string `json:"shasum"`
+	} `json:"dist"`
+	Source struct {
+		Type string `json:"type"`
+	} `json:"source"`
+}
+
+type composerLock struct {
+	Packages    []composerLockPackage `json:"packages"`
+	PackagesDev []composerLockPackage `json:"packages-dev"`
+}
+
+type composerManifest struct {
+	Require    map[string]string `json:"require"`
+	RequireDev map[string]string `json:"require-dev"`
+}
+
+// DiscoverComposer reads composer.lock. packages-dev entries are flagged Dev
+// and composer.json, when present, tells which packages are required directly.
+func DiscoverComposer(dir string) []PackageRef {
+	b, err := os.ReadFile(filepath.Join(dir, "composer.lock"))
+	if err != nil {
+		return nil
+	}
+	var lock composerLock
+	if json.Unmarshal(b, &lock) != nil {
+		return nil
+	}
+
+	g := newLockGraph()
+	add := func(pkgs []composerLockPackage, dev bool) {
+		for _, p := range pkgs {
+			n := &lockNode{ref: PackageRef{
+				Ecosystem: "composer",
+				Name:      p.Name,
+				Version:   p.Version,
+				Source:    "composer.lock",
+				Integrity: p.Dist.Shasum,
+				Dev:       dev,
+			}}
+			switch {
+			case p.Dist.Type == "path":
+				n.ref.Kind = "path"
+			case p.Dist.Type == "" && p.Source.Type != "":
+				n.ref.Kind = "vcs"
+			}
+			for _, dep := range sortedKeys(p.Require) {
+				if composerPlatformPackage(dep) {
+					continue
+				}
+				n.deps = append(n.deps, lockEdge{key: strings.ToLower(dep)})
+			}
+			g.add([]string{strings.ToLower(p.Name)}, n)
+		}
+	}
+	add(lock.Packages, false)
+	add(lock.PackagesDev, true)
+
+	var prodRoots, devRoots []string
+	if mb, err := os.ReadFile(filepath.Join(dir, "composer.json")); err == nil {
+		var manifest composerManifest
+		if json.Unmarshal(mb, &manifest) == nil {
+			for name := range manifest.Require {
+				prodRoots = append(prodRoots, strings.ToLower(name))
+			}
+			for name := range manifest.RequireDev {
+				devRoots = append(devRoots, strings.ToLower(name))
+			}
+		}
+	}
+
+	refs := g.refs(prodRoots, devRoots)
+	sort.SliceStable(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	return refs
+}
+
+// composerPlatformPackage reports requirements on the PHP runtime and its
+// extensions, which are not installable packages.
+func composerPlatformPackage(name string) bool {
+	name = strings.ToLower(name)
+	return name == "php" || name == "php-64bit" || name == "hhvm" || name == "composer" ||
+		name == "composer-plugin-api" || name == "composer-runtime-api" ||
+		strings.HasPrefix(name, "ext-") || strings.HasPrefix(name, "lib-")
+}
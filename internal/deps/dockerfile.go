@@ -0,0 +1,222 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var dockerVarRe = regexp.MustCompile(`\$(?:\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?[-+])([^}]*))?\}|([A-Za-z_][A-Za-z0-9_]*))`)
+
+// dockerfileStage is one FROM of a multi-stage build.
+type dockerfileStage struct {
+	name  string // "AS" name, if any
+	image string // image or earlier stage it starts from, build arguments expanded
+	unset []string
+}
+
+// DiscoverDockerfiles lists the images the Dockerfiles and Containerfiles
+// of dir build from: FROM lines, with build arguments declared before the
+// first FROM expanded to their defaults, and images copied from with
+// COPY --from or mounted with RUN --mount=from. Stages that start from an
+// earlier stage are followed, so the image the final stage is based on is
+// reported as "base image" and the others as "build stage". References not
+// pinned to a digest have Unpinned set.
+func DiscoverDockerfiles(dir string) []PackageRef {
+	var files []string
+	for _, pattern := range []string{"Dockerfile", "Containerfile", "Dockerfile.*", "Containerfile.*", "*.Dockerfile", "*.dockerfile", "*.Containerfile"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		for _, m := range matches {
+			if !strings.HasSuffix(m, ".dockerignore") && !containsString(files, m) && fileExists(m) {
+				files = append(files, m)
+			}
+		}
+	}
+	sort.Strings(files)
+
+	var refs []PackageRef
+	for _, path := range files {
+		refs = append(refs, readDockerfile(path, relSource(dir, path))...)
+	}
+	return refs
+}
+
+func readDockerfile(path, source string) []PackageRef {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	args := make(map[string]string) // global build arguments
+	var stages []dockerfileStage
+	var copied []dockerfileStage // COPY --from / RUN --mount=from images, with the stage using them as name
+
+	for _, inst := range dockerInstructions(string(b)) {
+		fields := strings.Fields(inst)
+		if len(fields) < 2 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "ARG":
+			if len(stages) > 0 {
+				continue // stage arguments do not apply to FROM
+			}
+			for _, a := range fields[1:] {
+				name, value, _ := strings.Cut(a, "=")
+				args[name] = strings.Trim(value, `"'`)
+			}
+		case "FROM":
+			var st dockerfileStage
+			rest := fields[1:]
+			for len(rest) > 0 && strings.HasPrefix(rest[0], "--") {
+				rest = rest[1:] // --platform
+			}
+			if len(rest) == 0 {
+				continue
+			}
+			st.image, st.unset = expandDockerArgs(rest[0], args)
+			if len(rest) >= 3 && strings.EqualFold(rest[1], "AS") {
+				st.name = rest[2]
+			}
+			stages = append(stages, st)
+		case "COPY", "ADD", "RUN":
+			if len(stages) == 0 {
+				continue
+			}
+			for _, f := range fields[1:] {
+				if !strings.HasPrefix(f, "--") {
+					break
+				}
+				var from string
+				if v, ok := strings.CutPrefix(f, "--from="); ok {
+					from = v
+				} else if v, ok := strings.CutPrefix(f, "--mount="); ok {
+					for _, kv := range strings.Split(v, ",") {
+						if v, ok := strings.CutPrefix(kv, "from="); ok {
+							from = v
+						}
+					}
+				}
+				if from != "" {
+					image, unset := expandDockerArgs(from, args)
+					copied = append(copied, dockerfileStage{name: stages[len(stages)-1].name, image: image, unset: unset})
+				}
+			}
+		}
+	}
+
+	// stage names and indexes refer to earlier stages, not images
+	stageIndex := func(ref string) int {
+		if n, err := strconv.Atoi(ref); err == nil && n >= 0 && n < len(stages) {
+			return n
+		}
+		for i, st := range stages {
+			if st.name != "" && strings.EqualFold(st.name, ref) {
+				return i
+			}
+		}
+		return -1
+	}
+	base := -1 // stage whose image the final image is built on
+	for i := len(stages) - 1; i >= 0; {
+		j := stageIndex(stages[i].image)
+		if j < 0 || j >= i {
+			base = i
+			break
+		}
+		i = j
+	}
+
+	var refs []PackageRef
+	add := func(st dockerfileStage, scope string) {
+		if st.image == "" || st.image == "scratch" || stageIndex(st.image) >= 0 {
+			return
+		}
+		ref := PackageRef{Ecosystem: "docker", Source: source, Direct: true, NativeScope: scope}
+		ref.Name, ref.Version = splitImageRef(st.image)
+		if st.name != "" {
+			ref.Groups = []string{st.name}
+		}
+		switch {
+		case len(st.unset) > 0:
+			ref.Unpinned = "build argument " + strings.Join(st.unset, ", ") + " has no default"
+		case !strings.HasPrefix(ref.Version, "sha256:"):
+			ref.Unpinned = "tag " + ref.Version + ", not a digest"
+		}
+		refs = append(refs, ref)
+	}
+	for i, st := range stages {
+		if i == base {
+			add(st, "base image")
+		} else {
+			add(st, "build stage")
+		}
+	}
+	for _, st := range copied {
+		add(st, "copied from")
+	}
+	return refs
+}
+
+// dockerInstructions splits a Dockerfile into instructions, joining
+// continuation lines and dropping comments and parser directives.
+func dockerInstructions(content string) []string {
+	escape := `\`
+	var out []string
+	var cur strings.Builder
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			if i < 5 && strings.HasPrefix(strings.ToLower(strings.ReplaceAll(trimmed, " ", "")), "#escape=") {
+				escape = strings.TrimSpace(trimmed[strings.Index(trimmed, "=")+1:])
+			}
+			continue
+		}
+		if cont, ok := strings.CutSuffix(strings.TrimRight(line, " \t"), escape); ok && escape != "" {
+			cur.WriteString(cont + " ")
+			continue
+		}
+		cur.WriteString(line)
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			out = append(out, s)
+		}
+		cur.Reset()
+	}
+	if s := strings.TrimSpace(cur.String()); s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+// expandDockerArgs substitutes $VAR, ${VAR}, ${VAR:-default} and
+// ${VAR:+alternative} from args, returning the names that have no value.
+// References to those are left as they are.
+func expandDockerArgs(s string, args map[string]string) (string, []string) {
+	var unset []string
+	out := dockerVarRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := dockerVarRe.FindStringSubmatch(m)
+		name := sub[1] + sub[4]
+		value, ok := args[name]
+		set := ok && value != ""
+		switch sub[2] {
+		case ":-", "-":
+			if !set {
+				return sub[3]
+			}
+		case ":+", "+":
+			if set {
+				return sub[3]
+			}
+			return ""
+		}
+		if !set {
+			unset = append(unset, name)
+			return m // keep the reference visible in the image name
+		}
+		return value
+	})
+	return out, unset
+}
@@ -0,0 +1,256 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	gemSpecRe      = regexp.MustCompile(`^([^\s(!]+)(?: \(([^)]*)\))?(!)?$`)
+	gemGroupRe     = regexp.MustCompile(`^group\s+(.+?)\s+do\b`)
+	gemInlineRe    = regexp.MustCompile(`^gem\s+["']([^"']+)["'](.*)$`)
+	gemGroupNameRe = regexp.MustCompile(`:(\w+)`)
+	gemPlatformRe  = regexp.MustCompile(`^(x86_64|x86|i[3-6]86|x64|arm64|aarch64|arm|armv\w+|universal|powerpc\w*|ppc\w*|s390x?|sparc\w*|mips\w*|riscv64)-[a-z]\w*(-\w+)*$`)
+)
+
+// DiscoverGems reads Gemfile.lock. Gems from GIT and PATH sources are marked
+// as such, DEPENDENCIES gives the direct gems, and platform-specific variants
+// (nokogiri-1.15.4-x86_64-linux) are folded into one package whose platforms
+// are recorded in Markers. Development/test groups come from the Gemfile.
+func DiscoverGems(dir string) []PackageRef {
+	b, err := os.ReadFile(filepath.Join(dir, "Gemfile.lock"))
+	if err != nil {
+		return nil
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n")
+	platforms := gemLockPlatforms(lines)
+
+	g := newLockGraph()
+	var direct []string
+	checksums := make(map[string]string)
+	variants := make(map[*lockNode][]string)
+
+	var section, remote string
+	var current *lockNode
+	for _, raw := range lines {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+		line := strings.TrimSpace(raw)
+		if indent == 0 {
+			section, remote, current = strings.Fields(line)[0], "", nil
+			continue
+		}
+
+		switch section {
+		case "GEM", "GIT", "PATH":
+			switch {
+			case indent == 2 && strings.HasPrefix(line, "remote:"):
+				remote = strings.TrimSpace(strings.TrimPrefix(line, "remote:"))
+			case indent == 4:
+				m := gemSpecRe.FindStringSubmatch(line)
+				if m == nil {
+					current = nil
+					continue
+				}
+				version, platform := gemSplitPlatform(m[2], platforms)
+				if section == "PATH" && remote == "." {
+					// a gemspec of the project itself; its dependencies are direct,
+					// and a project with several gemspecs has them all under one key
+					if current = g.nodes["\x00self"]; current == nil {
+						current = &lockNode{}
+						g.add([]string{"\x00self"}, current)
+					}
+					continue
+				}
+				key := m[1]
+				if n, ok := g.nodes[key]; ok && n.ref.Version == version {
+					current = n // another platform variant of the same gem
+				} else {
+					current = &lockNode{ref: PackageRef{
+						Ecosystem: "gem",
+						Name:      m[1],
+						Version:   version,
+						Source:    "Gemfile.lock",
+					}}
+					switch section {
+					case "GIT":
+						current.ref.Kind = "vcs"
+					case "PATH":
+						current.ref.Kind = "path"
+					}
+					g.add([]string{key}, current)
+				}
+				if platform != "" {
+					variants[current] = append(variants[current], platform)
+				}
+			case indent == 6 && current != nil:
+				if m := gemSpecRe.FindStringSubmatch(line); m != nil {
+					edge := lockEdge{key: m[1]}
+					if !containsEdge(current.deps, edge) {
+						current.deps = append(current.deps, edge)
+					}
+				}
+			}
+		case "DEPENDENCIES":
+			if m := gemSpecRe.FindStringSubmatch(line); m != nil {
+				direct = append(direct, m[1])
+			}
+		case "CHECKSUMS":
+			// rack (2.2.8) sha256=...; platform variants share the first one seen
+			spec, sum, ok := strings.Cut(line, ") ")
+			if m := gemSpecRe.FindStringSubmatch(spec + ")"); ok && m != nil {
+				version, _ := gemSplitPlatform(m[2], platforms)
+				if _, seen := checksums[m[1]+"@"+version]; !seen {
+					checksums[m[1]+"@"+version] = strings.TrimSpace(sum)
+				}
+			}
+		}
+	}
+
+	for n, vs := range variants {
+		// a pure-Ruby build alongside native ones works on any platform
+		if len(platforms) > 0 && !containsString(vs, "ruby") {
+			sort.Strings(vs)
+			n.ref.Markers = "platform in " + strings.Join(vs, ",")
+		}
+	}
+	if self := g.nodes["\x00self"]; self != nil {
+		for _, e := range self.deps {
+			direct = append(direct, e.key)
+		}
+		delete(g.nodes, "\x00self")
+	}
+
+	groups := readGemfileGroups(filepath.Join(dir, "Gemfile"))
+	var prodRoots, devRoots []string
+	for _, name := range direct {
+		if gemDevGroups(groups[name]) {
+			devRoots = append(devRoots, name)
+		} else {
+			prodRoots = append(prodRoots, name)
+		}
+	}
+
+	refs := g.refs(prodRoots, devRoots)
+	for i := range refs {
+		refs[i].Groups = groups[refs[i].Name]
+		if sum, ok := checksums[refs[i].Name+"@"+refs[i].Version]; ok {
+			refs[i].Integrity = sum
+		}
+	}
+	sort.SliceStable(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	return refs
+}
+
+// gemLockPlatforms returns the PLATFORMS section of a Gemfile.lock, which
+// comes after the specs that need it.
+func gemLockPlatforms(lines []string) []string {
+	var platforms []string
+	section := ""
+	for _, raw := range lines {
+		line := strings.TrimSpace(raw)
+		switch {
+		case line == "":
+		case !strings.HasPrefix(raw, " "):
+			section = line
+		case section == "PLATFORMS":
+			platforms = append(platforms, line)
+		}
+	}
+	return platforms
+}
+
+// gemSplitPlatform splits "1.15.4-x86_64-linux" into version and platform.
+// A prerelease version may contain '-' as well ("1.0.0-rc1"), so only a
+// suffix that is one of the lockfile's platforms or has the cpu-os form of
+// one is taken as the platform.
+func gemSplitPlatform(v string, platforms []string) (string, string) {
+	v = strings.TrimSpace(v)
+	if strings.ContainsAny(v, "<>=~ ") {
+		return v, ""
+	}
+	for i := 1; i < len(v); i++ {
+		if v[i] == '-' && (containsString(platforms, v[i+1:]) || gemPlatformRe.MatchString(v[i+1:])) {
+			return v[:i], v[i+1:]
+		}
+	}
+	return v, ""
+}
+
+func containsEdge(edges []lockEdge, e lockEdge) bool {
+	for _, x := range edges {
+		if x == e {
+			return true
+		}
+	}
+	return false
+}
+
+// readGemfileGroups maps gem names to the Bundler groups they are declared
+// in, from "group :development, :test do" blocks and group:/groups: options.
+func readGemfileGroups(path string) map[string][]string {
+	groups := make(map[string][]string)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return groups
+	}
+	var stack [][]string // groups of the enclosing blocks
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		switch {
+		case gemGroupRe.MatchString(line):
+			var names []string
+			for _, m := range gemGroupNameRe.FindAllStringSubmatch(gemGroupRe.FindStringSubmatch(line)[1], -1) {
+				names = append(names, m[1])
+			}
+			stack = append(stack, names)
+		case strings.HasSuffix(line, " do") || strings.Contains(line, " do |"):
+			stack = append(stack, nil) // platforms/source/etc. blocks
+		case line == "end":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		default:
+			m := gemInlineRe.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			var names []string
+			for _, s := range stack {
+				names = append(names, s...)
+			}
+			if opt := m[2]; strings.Contains(opt, "group") {
+				after := opt[strings.Index(opt, "group"):]
+				for _, g := range gemGroupNameRe.FindAllStringSubmatch(after, -1) {
+					names = append(names, g[1])
+				}
+			}
+			if len(names) > 0 {
+				groups[m[1]] = names
+			}
+		}
+	}
+	return groups
+}
+
+// gemDevGroups reports whether a gem only belongs to development/test groups.
+func gemDevGroups(groups []string) bool {
+	if len(groups) == 0 {
+		return false
+	}
+	for _, g := range groups {
+		if g != "development" && g != "test" {
+			return false
+		}
+	}
+	return true
+}
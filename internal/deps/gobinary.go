@@ -0,0 +1,101 @@
+package deps
+
+import (
+	"debug/buildinfo"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+)
+
+// GoBinary is the build information a Go executable carries.
+type GoBinary struct {
+	File      string // path of the executable, relative to the scanned path
+	GoVersion string
+	Main      GoModule
+	Modules   []GoModule // dependencies linked into the binary, with their go.sum hashes
+	Settings  []debug.BuildSetting
+}
+
+// Setting returns a build setting ("CGO_ENABLED", "GOOS", "vcs.revision").
+func (b GoBinary) Setting(key string) string {
+	for _, s := range b.Settings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// ReadGoBinaries reads the embedded build info of a Go executable, or of
+// every Go executable below a directory; other files are skipped. The
+// standard library's debug/buildinfo handles ELF, PE, Mach-O and XCOFF.
+func ReadGoBinaries(path string) []GoBinary {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	if !st.IsDir() {
+		if b, ok := readGoBinary(path, filepath.Base(path)); ok {
+			return []GoBinary{b}
+		}
+		return nil
+	}
+
+	var bins []GoBinary
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if b, ok := readGoBinary(p, relSource(path, p)); ok {
+			bins = append(bins, b)
+		}
+		return nil
+	})
+	return bins
+}
+
+func readGoBinary(path, name string) (GoBinary, bool) {
+	bi, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return GoBinary{}, false
+	}
+	b := GoBinary{
+		File:      name,
+		GoVersion: bi.GoVersion,
+		Main:      goBinaryModule(&bi.Main),
+		Settings:  bi.Settings,
+	}
+	b.Main.Main = true
+	b.Main.GoVersion = strings.TrimPrefix(bi.GoVersion, "go")
+	for _, dep := range bi.Deps {
+		b.Modules = append(b.Modules, goBinaryModule(dep))
+	}
+	return b, true
+}
+
+func goBinaryModule(m *debug.Module) GoModule {
+	mod := GoModule{Path: m.Path, Version: m.Version, Sum: m.Sum, Scope: ScopeRuntime}
+	if r := m.Replace; r != nil {
+		mod.Replace = strings.TrimSpace(r.Path + " " + r.Version)
+		if r.Sum != "" {
+			mod.Sum = r.Sum
+		}
+	}
+	return mod
+}
+
+// GoBinaryModules returns the modules of the binaries as a build list per
+// binary: its main module followed by its dependencies, attributed to the
+// executable through Subproject.
+func GoBinaryModules(bins []GoBinary) []GoModule {
+	var modules []GoModule
+	for _, b := range bins {
+		for _, m := range append([]GoModule{b.Main}, b.Modules...) {
+			m.Subproject = b.File
+			modules = append(modules, m)
+		}
+	}
+	return modules
+}
@@ -1,28 +1,244 @@
 package deps
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
 )
 
 type GoModule struct {
 	Path    string
 	Version string
-	Replace string
-	Dir     string
+	Replace string // replacement module ("path version") or local directory
+	Dir     string // local, vendored or module cache directory, when known
+
+	Main       bool     // the module whose go.mod was read
+	Indirect   bool     // not imported by the main module itself ("// indirect")
+	Scope      string   // runtime, test or build by what imports it, "" when unknown; see classifyGoScopes
+	Sum        string   // go.sum hash of the module content (h1:...)
+	SumMissing bool     // go.sum exists but has no entry for the module at all
+	Vendored   bool     // listed in vendor/modules.txt
+	Parents    []string // modules of the build list that require it, per `go mod graph`
+
+	// Main module only
+	GoVersion string   // go directive
+	Toolchain string   // toolchain directive
+	Retracted []string // versions (or [low, high] ranges) the module retracts
+
+	Subproject string // directory of the go.mod that lists it ("" for the root module)
 }
 
+// DiscoverGoModules reads go.mod without a Go toolchain: requirements with
+// their "// indirect" marking, replace and exclude directives, checked
+// against go.sum and vendor/modules.txt. Only when go.mod cannot be parsed is
+// "go list -m all" tried, if a toolchain is installed.
 func DiscoverGoModules(dir string) []GoModule {
-	p := filepath.Join(dir, "go.mod")
-	if _, err := os.Stat(p); err != nil {
+	return readGoModule(dir, goWorkContext{})
+}
+
+// goWorkContext is what a go.work adds to the modules it uses.
+type goWorkContext struct {
+	dir      string             // directory of go.work
+	replaces []*modfile.Replace // take precedence over the module's own
+	sums     map[string]string  // go.work.sum
+	modules  map[string]string  // module path -> directory of the workspace modules
+	graph    []byte             // `go mod graph` output, the same for every module of the workspace
+}
+
+func readGoModule(dir string, ws goWorkContext) []GoModule {
+	b, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
 		return nil
 	}
+	f, err := modfile.Parse("go.mod", b, nil)
+	if err != nil || f.Module == nil {
+		return goListModules(dir)
+	}
+
+	main := GoModule{Path: f.Module.Mod.Path, Main: true, Dir: dir}
+	if f.Go != nil {
+		main.GoVersion = f.Go.Version
+	}
+	if f.Toolchain != nil {
+		main.Toolchain = f.Toolchain.Name
+	}
+	for _, r := range f.Retract {
+		if r.Low == r.High {
+			main.Retracted = append(main.Retracted, r.Low)
+		} else {
+			main.Retracted = append(main.Retracted, "["+r.Low+", "+r.High+"]")
+		}
+	}
+
+	excluded := make(map[string]bool)
+	for _, x := range f.Exclude {
+		excluded[x.Mod.Path+"@"+x.Mod.Version] = true
+	}
+	sums, hasSums := readGoSum(filepath.Join(dir, "go.sum"))
+	for k, v := range ws.sums {
+		if _, ok := sums[k]; !ok {
+			sums[k] = v
+		}
+	}
+	vendored := readVendorModules(filepath.Join(dir, "vendor", "modules.txt"))
 
-	cmd := exec.Command("go", "list", "-m", "-f", "{{.Path}} {{.Version}} {{.Replace}} {{.Dir}}", "all")
+	modules := []GoModule{main}
+	seen := map[string]bool{main.Path: true}
+	add := func(path, version string, indirect bool) {
+		if seen[path] || excluded[path+"@"+version] {
+			return
+		}
+		seen[path] = true
+		m := GoModule{Path: path, Version: version, Indirect: indirect}
+
+		sumKey := path + " " + version
+		r, base := goReplacement(ws.replaces, path, version), ws.dir
+		if r == nil {
+			r, base = goReplacement(f.Replace, path, version), dir
+		}
+		if wsDir, ok := ws.modules[path]; ok {
+			// another module of the workspace: built from its directory
+			m.Replace, m.Dir, sumKey = relSource(dir, wsDir), wsDir, ""
+		} else if r != nil {
+			if r.New.Version == "" {
+				m.Replace = r.New.Path
+				m.Dir = r.New.Path
+				if !filepath.IsAbs(m.Dir) {
+					m.Dir = filepath.Join(base, filepath.FromSlash(m.Dir))
+				}
+				sumKey = "" // local directories are not checksummed
+			} else {
+				m.Replace = r.New.Path + " " + r.New.Version
+				sumKey = r.New.Path + " " + r.New.Version
+				seen[r.New.Path] = true // its go.sum lines belong to this module
+			}
+		}
+		if _, ok := vendored[path]; ok {
+			m.Vendored = true
+			m.Dir = filepath.Join(dir, "vendor", filepath.FromSlash(path))
+		}
+		if sumKey != "" {
+			m.Sum = sums[sumKey]
+			_, listed := sums[sumKey+"/go.mod"]
+			m.SumMissing = hasSums && m.Sum == "" && !listed
+		}
+		modules = append(modules, m)
+	}
+
+	for _, r := range f.Require {
+		add(r.Mod.Path, r.Mod.Version, r.Indirect)
+	}
+	// vendor/modules.txt and, before Go 1.17 (when go.mod only lists direct
+	// requirements), go.sum name the rest of the build list
+	for _, path := range sortedKeys(vendored) {
+		add(path, vendored[path], true)
+	}
+	if main.GoVersion == "" || semver.Compare("v"+main.GoVersion, "v1.17") < 0 {
+		latest := make(map[string]string)
+		for key := range sums {
+			path, version, ok := strings.Cut(key, " ")
+			if !ok || strings.HasSuffix(version, "/go.mod") {
+				continue
+			}
+			if semver.Compare(version, latest[path]) > 0 {
+				latest[path] = version
+			}
+		}
+		for _, path := range sortedKeys(latest) {
+			add(path, latest[path], true)
+		}
+	}
+
+	sort.SliceStable(modules[1:], func(i, j int) bool {
+		return modules[1+i].Path < modules[1+j].Path
+	})
+	out := ws.graph
+	if ws.dir == "" {
+		out = goModGraph(dir)
+	}
+	graph := parseGoModGraph(out, modules)
+	classifyGoScopes(dir, f, modules, graph)
+	setGoParents(modules, graph)
+	return modules
+}
+
+// goReplacement returns the replace directive that applies to path@version;
+// one naming the exact version wins over one for all versions.
+func goReplacement(replaces []*modfile.Replace, path, version string) *modfile.Replace {
+	var wildcard *modfile.Replace
+	for _, r := range replaces {
+		if r.Old.Path != path {
+			continue
+		}
+		if r.Old.Version == version {
+			return r
+		}
+		if r.Old.Version == "" {
+			wildcard = r
+		}
+	}
+	return wildcard
+}
+
+// readGoSum maps "path version" (and "path version/go.mod") to the hash
+// recorded in go.sum.
+func readGoSum(path string) (map[string]string, bool) {
+	sums := make(map[string]string)
+	f, err := os.Open(path)
+	if err != nil {
+		return sums, false
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 3 {
+			sums[fields[0]+" "+fields[1]] = fields[2]
+		}
+	}
+	return sums, true
+}
+
+// readVendorModules maps module paths to versions from vendor/modules.txt
+// ("# path version" and "# path version => replacement" lines).
+func readVendorModules(path string) map[string]string {
+	mods := make(map[string]string)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return mods
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if !strings.HasPrefix(line, "# ") {
+			continue
+		}
+		fields := strings.Fields(line[2:])
+		if len(fields) == 0 {
+			continue
+		}
+		version := ""
+		if len(fields) > 1 && fields[1] != "=>" {
+			version = fields[1]
+		}
+		mods[fields[0]] = version
+	}
+	return mods
+}
+
+// goListModules asks the Go toolchain for the build list.
+func goListModules(dir string) []GoModule {
+	if _, err := exec.LookPath("go"); err != nil {
+		return nil
+	}
+	cmd := exec.Command("go", "list", "-m", "-json", "all")
 	cmd.Dir = dir
 	out, err := cmd.Output()
 	if err != nil {
@@ -30,28 +246,263 @@ func DiscoverGoModules(dir string) []GoModule {
 	}
 
 	var modules []GoModule
-	re := regexp.MustCompile(`^(\S+)\s+(\S+)\s+(\S*)\s*(.*)$`)
-	for _, line := range strings.Split(string(out), "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var m struct {
+			Path, Version, Dir string
+			Main, Indirect     bool
+			Replace            *struct{ Path, Version string }
 		}
-		m := re.FindStringSubmatch(line)
-		if m != nil {
-			var replStr string
-			if m[3] != "<nil>" {
-				replStr = m[3]
-			}
-			modules = append(modules, GoModule{
-				Path:    m[1],
-				Version: m[2],
-				Replace: replStr,
-				Dir:     strings.TrimSpace(m[4]),
-			})
+		if dec.Decode(&m) != nil {
+			break
+		}
+		mod := GoModule{Path: m.Path, Version: m.Version, Dir: m.Dir, Main: m.Main, Indirect: m.Indirect}
+		if m.Replace != nil {
+			mod.Replace = strings.TrimSpace(m.Replace.Path + " " + m.Replace.Version)
 		}
+		modules = append(modules, mod)
 	}
-	sort.Slice(modules, func(i, j int) bool {
+	sort.SliceStable(modules, func(i, j int) bool {
+		if modules[i].Main != modules[j].Main {
+			return modules[i].Main
+		}
 		return modules[i].Path < modules[j].Path
 	})
+	graph := parseGoModGraph(goModGraph(dir), modules)
+	classifyGoScopes(dir, nil, modules, graph)
+	setGoParents(modules, graph)
 	return modules
 }
+
+// GoWorkspace is a go.work file and the modules it uses.
+type GoWorkspace struct {
+	Dir       string   // directory of go.work, relative to the scanned root ("" for the root)
+	GoVersion string   // go directive
+	Toolchain string   // toolchain directive
+	Modules   []string // paths of the modules in "use" directives
+	Replaces  []string // workspace-level replace directives ("old => new")
+
+	// Merged is the workspace build list: the workspace modules followed by
+	// the highest version of each module any of them requires.
+	Merged []GoModule
+}
+
+// DiscoverGo reports the build list of the go.mod in dir or, when dir has
+// a go.work, of each module it uses, read in workspace mode: its replaces
+// and go.work.sum apply, and requirements on each other resolve to the
+// workspace directories. What a PackageRef has no room for (main modules,
+// replacements, the go.work and its merged build list) is recorded in the
+// GoBuild Collection.Discover passes in opts.
+func DiscoverGo(dir string, opts Options) []PackageRef {
+	rel := "."
+	if opts.root != "" {
+		rel = relSource(opts.root, dir)
+	}
+	var modules []GoModule
+	var workspace []string
+	if ws, mods, ok := readGoWork(dir, dir); ok {
+		modules, workspace = mods, ws.Modules
+		ws.Dir = joinSubproject(rel, "")
+		for i := range ws.Merged {
+			ws.Merged[i].Subproject = ws.Dir
+		}
+		opts.goBuild.addWorkspace(ws)
+	}
+	used := false // the go.mod in dir, by the go.work
+	for _, m := range modules {
+		used = used || m.Main && m.Subproject == ""
+	}
+	if !used {
+		modules = append(modules, DiscoverGoModules(dir)...)
+	}
+
+	var refs []PackageRef
+	for _, m := range modules {
+		if !m.Main && containsString(workspace, m.Path) {
+			continue // a main module of its own
+		}
+		if p, ok := goModuleRef(m); ok {
+			p.Source = "go.mod"
+			if m.Vendored {
+				p.Location = path.Join(m.Subproject, "vendor", m.Path)
+			}
+			refs = append(refs, p)
+		}
+		m.Subproject = joinSubproject(rel, m.Subproject)
+		opts.goBuild.add(m)
+	}
+	return refs
+}
+
+// goWorkspaceDirs returns the directories of the modules a go.work in dir
+// uses, other than dir itself; DiscoverGo reads them with the go.work.
+func goWorkspaceDirs(dir string) []string {
+	b, err := os.ReadFile(filepath.Join(dir, "go.work"))
+	if err != nil {
+		return nil
+	}
+	wf, err := modfile.ParseWork("go.work", b, nil)
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, u := range wf.Use {
+		if udir := goUseDir(dir, u.Path); udir != filepath.Clean(dir) {
+			dirs = append(dirs, udir)
+		}
+	}
+	return dirs
+}
+
+// goUseDir resolves the path of a go.work use directive.
+func goUseDir(dir, use string) string {
+	udir := filepath.FromSlash(use)
+	if !filepath.IsAbs(udir) {
+		udir = filepath.Join(dir, udir)
+	}
+	return udir
+}
+
+// goModuleRef describes a module of a build list other than a main module
+// as a package. A replacement is recorded as the package's repository, or
+// as a path dependency when it is a local directory; a module go.sum does
+// not list is reported as unpinned.
+func goModuleRef(m GoModule) (PackageRef, bool) {
+	if m.Main {
+		return PackageRef{}, false
+	}
+	p := PackageRef{
+		Ecosystem:  goEcosystem.ID,
+		Name:       m.Path,
+		Version:    m.Version,
+		Integrity:  m.Sum,
+		Direct:     !m.Indirect,
+		Parents:    m.Parents,
+		Scope:      m.Scope,
+		Subproject: m.Subproject,
+		Repository: m.Replace,
+	}
+	if m.Replace != "" && !strings.Contains(m.Replace, " ") {
+		p.Kind = "path"
+	}
+	if m.SumMissing {
+		p.Unpinned = "not in go.sum"
+	}
+	return p, true
+}
+
+// GoBuild holds what Go discovery finds besides packages: the main modules
+// (whose go.mod was read, or that a binary was built from), the go.work
+// files, and the GoModule each package was made from.
+type GoBuild struct {
+	Mains      []GoModule
+	Workspaces []GoWorkspace
+
+	modules map[string]GoModule // by Subproject and Path
+}
+
+// Module returns the GoModule behind a Go package, with the replacement
+// and go.sum hash its PackageRef only summarises.
+func (b *GoBuild) Module(p PackageRef) (GoModule, bool) {
+	if b == nil || p.Ecosystem != goEcosystem.ID {
+		return GoModule{}, false
+	}
+	m, ok := b.modules[p.Subproject+"\x00"+p.Name]
+	return m, ok
+}
+
+func (b *GoBuild) add(m GoModule) {
+	if b == nil {
+		return
+	}
+	if m.Main {
+		b.Mains = append(b.Mains, m)
+		return
+	}
+	if b.modules == nil {
+		b.modules = make(map[string]GoModule)
+	}
+	b.modules[m.Subproject+"\x00"+m.Path] = m
+}
+
+func (b *GoBuild) addWorkspace(ws GoWorkspace) {
+	if b != nil {
+		b.Workspaces = append(b.Workspaces, ws)
+	}
+}
+
+// readGoWork reads the go.work in dir and the modules it uses.
+func readGoWork(root, dir string) (GoWorkspace, []GoModule, bool) {
+	b, err := os.ReadFile(filepath.Join(dir, "go.work"))
+	if err != nil {
+		return GoWorkspace{}, nil, false
+	}
+	wf, err := modfile.ParseWork("go.work", b, nil)
+	if err != nil {
+		return GoWorkspace{}, nil, false
+	}
+
+	ws := GoWorkspace{Dir: relSource(root, dir)}
+	if ws.Dir == "." {
+		ws.Dir = ""
+	}
+	if wf.Go != nil {
+		ws.GoVersion = wf.Go.Version
+	}
+	if wf.Toolchain != nil {
+		ws.Toolchain = wf.Toolchain.Name
+	}
+	ctx := goWorkContext{dir: dir, replaces: wf.Replace, modules: make(map[string]string)}
+	ctx.sums, _ = readGoSum(filepath.Join(dir, "go.work.sum"))
+	for _, r := range wf.Replace {
+		ws.Replaces = append(ws.Replaces, strings.TrimSpace(r.Old.Path+" "+r.Old.Version)+" => "+strings.TrimSpace(r.New.Path+" "+r.New.Version))
+	}
+
+	var useDirs []string
+	for _, u := range wf.Use {
+		udir := goUseDir(dir, u.Path)
+		mb, err := os.ReadFile(filepath.Join(udir, "go.mod"))
+		if err != nil {
+			continue
+		}
+		if path := modfile.ModulePath(mb); path != "" {
+			ctx.modules[path] = udir
+			ws.Modules = append(ws.Modules, path)
+			useDirs = append(useDirs, udir)
+		}
+	}
+
+	if len(useDirs) > 0 {
+		ctx.graph = goModGraph(useDirs[0])
+	}
+
+	var modules []GoModule
+	merged := make(map[string]GoModule)
+	for _, udir := range useDirs {
+		sub := relSource(root, udir)
+		if sub == "." {
+			sub = ""
+		}
+		for _, m := range readGoModule(udir, ctx) {
+			m.Subproject = sub
+			modules = append(modules, m)
+
+			cur, ok := merged[m.Path]
+			switch {
+			case !ok, m.Main && !cur.Main, !cur.Main && semver.Compare(m.Version, cur.Version) > 0:
+				m.Indirect = m.Indirect && (!ok || cur.Indirect)
+				merged[m.Path] = m
+			case !m.Indirect:
+				cur.Indirect = false // required directly by some workspace module
+				merged[m.Path] = cur
+			}
+		}
+	}
+	for _, path := range sortedKeys(merged) {
+		m := merged[path]
+		m.Subproject = ws.Dir
+		ws.Merged = append(ws.Merged, m)
+	}
+	sort.SliceStable(ws.Merged, func(i, j int) bool { return ws.Merged[i].Main && !ws.Merged[j].Main })
+	return ws, modules, true
+}
@@ -0,0 +1,190 @@
+package deps
+
+import (
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// classifyGoScopes sets the Scope of the modules in a build list from the
+// imports of the main module's packages in dir: modules imported by
+// non-test files are runtime, those imported only from _test.go files are
+// test, and those only imported by tools.go-style files (the "tools"
+// build tag) or named by tool directives are build. Modules the imported
+// ones require, per `go mod graph`, get the widest scope of a module
+// requiring them (graph, see parseGoModGraph). Indirect modules nothing
+// reaches, which is all of them without a Go toolchain, are left without a
+// scope; other modules stay runtime, as do all of them when dir has no Go
+// files.
+func classifyGoScopes(dir string, f *modfile.File, modules []GoModule, graph map[string][]string) {
+	for i := range modules {
+		modules[i].Scope = ScopeRuntime
+	}
+
+	imports := map[string]string{} // import path -> widest scope importing it
+	found := false
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if p != dir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+				fileExists(filepath.Join(p, "go.mod"))) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") {
+			return nil
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), p, nil, parser.ImportsOnly|parser.ParseComments)
+		if err != nil {
+			return nil
+		}
+		found = true
+		scope := ScopeRuntime
+		if strings.HasSuffix(name, "_test.go") {
+			scope = ScopeTest
+		} else if toolsFile(file.Comments) {
+			scope = ScopeBuild
+		}
+		for _, imp := range file.Imports {
+			path := strings.Trim(imp.Path.Value, "\"`")
+			imports[path] = WiderScope(imports[path], scope)
+		}
+		return nil
+	})
+	if f != nil {
+		for _, t := range f.Tool {
+			imports[t.Path] = WiderScope(imports[t.Path], ScopeBuild)
+		}
+	}
+	if !found {
+		return
+	}
+
+	scopes := map[string]string{}
+	for path, scope := range imports {
+		if m := goModuleFor(path, modules); m != "" {
+			scopes[m] = WiderScope(scopes[m], scope)
+		}
+	}
+	// a module is needed wherever a module requiring it is
+	queue := sortedKeys(scopes)
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		for _, dep := range graph[path] {
+			if wider := WiderScope(scopes[dep], scopes[path]); wider != scopes[dep] {
+				scopes[dep] = wider
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	for i := range modules {
+		if modules[i].Main {
+			continue
+		}
+		if s, ok := scopes[modules[i].Path]; ok {
+			modules[i].Scope = s
+		} else if modules[i].Indirect {
+			modules[i].Scope = ""
+		}
+	}
+}
+
+// goModGraph returns the output of `go mod graph` in dir, or nil without a
+// Go toolchain.
+func goModGraph(dir string) []byte {
+	if _, err := exec.LookPath("go"); err != nil {
+		return nil
+	}
+	cmd := exec.Command("go", "mod", "graph")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+// parseGoModGraph returns, per module path, the paths the selected version
+// of the module requires according to `go mod graph` output.
+func parseGoModGraph(out []byte, modules []GoModule) map[string][]string {
+	selected := make(map[string]bool)
+	for _, m := range modules {
+		selected[m.Path+"@"+m.Version] = true
+	}
+	graph := make(map[string][]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		from, to, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok || !selected[from] {
+			continue
+		}
+		path, _, _ := strings.Cut(from, "@")
+		dep, _, _ := strings.Cut(to, "@")
+		graph[path] = append(graph[path], dep)
+	}
+	return graph
+}
+
+// setGoParents records, for each module other than the main module, the
+// modules of the build list whose selected version requires it.
+func setGoParents(modules []GoModule, graph map[string][]string) {
+	index := make(map[string]int)
+	for i, m := range modules {
+		index[m.Path] = i
+	}
+	for _, path := range sortedKeys(graph) {
+		if i, ok := index[path]; !ok || modules[i].Main {
+			continue
+		}
+		for _, dep := range graph[path] {
+			if i, ok := index[dep]; ok && !modules[i].Main && !containsString(modules[i].Parents, path) {
+				modules[i].Parents = append(modules[i].Parents, path)
+			}
+		}
+	}
+}
+
+// toolsFile reports whether a file is excluded from builds by a "tools"
+// build constraint, the convention for pinning tool dependencies.
+func toolsFile(comments []*ast.CommentGroup) bool {
+	for _, g := range comments {
+		for _, c := range g.List {
+			if !constraint.IsGoBuild(c.Text) && !constraint.IsPlusBuild(c.Text) {
+				continue
+			}
+			expr, err := constraint.Parse(c.Text)
+			if err != nil {
+				continue
+			}
+			// satisfied when the tools tag is set, but not without it
+			if expr.Eval(func(tag string) bool { return tag == "tools" }) && !expr.Eval(func(string) bool { return false }) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// goModuleFor returns the path of the module in the build list that
+// provides an import path: the longest module path that is a prefix of it.
+func goModuleFor(importPath string, modules []GoModule) string {
+	best := ""
+	for _, m := range modules {
+		if (importPath == m.Path || strings.HasPrefix(importPath, m.Path+"/")) && len(m.Path) > len(best) {
+			best = m.Path
+		}
+	}
+	return best
+}
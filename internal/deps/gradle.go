@@ -0,0 +1,452 @@
+package deps
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+var (
+	// implementation("g:a:v"), testImplementation 'g:a:v', api(platform("g:a:v")), implementation(libs.foo.bar)
+	gradleDeclRe = regexp.MustCompile(`(?m)^\s*([A-Za-z][A-Za-z0-9]*)\s*\(?\s*(?:(?:platform|enforcedPlatform)\s*\(\s*)?(?:["']([^"'\s]+)["']|(libs\.[A-Za-z0-9_.]+))`)
+	// implementation group: 'g', name: 'a', version: 'v'
+	gradleMapDeclRe = regexp.MustCompile(`(?m)^\s*([A-Za-z][A-Za-z0-9]*)\s*\(?\s*group\s*[:=]\s*["']([^"']+)["']\s*,\s*name\s*[:=]\s*["']([^"']+)["'](?:\s*,\s*version\s*[:=]\s*["']([^"']+)["'])?`)
+	// val kotlinVersion = "1.9.0", def guavaVersion = '32.1.0', ext.junitVersion = '5.10.0'
+	gradleVarRe    = regexp.MustCompile(`(?m)^\s*(?:val\s+|var\s+|def\s+|ext\.)?([A-Za-z_][A-Za-z0-9_]*)\s*=\s*["']([^"'$]+)["']`)
+	gradleInterpRe = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_.]*)\}?`)
+	gradleAliasSep = regexp.MustCompile(`[-_.]`)
+	gradleQuotedRe = regexp.MustCompile(`["']([^"']+)["']`)
+)
+
+// gradleDecl is a dependency declared in a build script or version catalog.
+type gradleDecl struct {
+	name          string // group:artifact
+	version       string
+	configuration string
+	source        string
+}
+
+// DiscoverGradle reads Gradle projects: dependency lockfiles (per
+// configuration), gradle/libs.versions.toml and, best-effort, the dependency
+// declarations in build.gradle / build.gradle.kts. Subprojects come from the
+// include statements in settings.gradle(.kts). Packages use the maven
+// ecosystem and carry their configurations in NativeScope.
+func DiscoverGradle(dir string) []PackageRef {
+	if !gradleProject(dir) {
+		return nil
+	}
+
+	props := readGradleProperties(filepath.Join(dir, "gradle.properties"))
+	catalog := readVersionCatalog(filepath.Join(dir, "gradle", "libs.versions.toml"))
+	used := make(map[string]bool) // catalog aliases referenced by a build script
+
+	var refs []PackageRef
+	for _, sub := range append([]string{""}, gradleSubprojects(dir)...) {
+		pdir := filepath.Join(dir, filepath.FromSlash(sub))
+		declared := readGradleBuild(dir, pdir, props, catalog, used)
+		locked := readGradleLockfiles(dir, pdir)
+
+		var projectRefs []PackageRef
+		if len(locked) > 0 {
+			direct := make(map[string]bool)
+			for _, d := range declared {
+				direct[d.name] = true
+			}
+			for _, ref := range locked {
+				ref.Direct = direct[ref.Name]
+				projectRefs = append(projectRefs, ref)
+			}
+		} else {
+			for _, d := range declared {
+				projectRefs = append(projectRefs, gradleDeclRef(d))
+			}
+		}
+		for _, ref := range projectRefs {
+			ref.Subproject = sub
+			refs = append(refs, ref)
+		}
+	}
+
+	// catalog entries no build script refers to (e.g. used through bundles or
+	// convention plugins) are still what the project pins
+	for _, alias := range sortedKeys(catalog) {
+		if used[alias] || strings.HasPrefix(alias, "bundles.") {
+			continue
+		}
+		d := catalog[alias]
+		if containsRef(refs, d.name) {
+			continue
+		}
+		refs = append(refs, gradleDeclRef(d))
+	}
+	return refs
+}
+
+func gradleProject(dir string) bool {
+	for _, f := range []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts", "gradle.lockfile"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func gradleDeclRef(d gradleDecl) PackageRef {
+	return PackageRef{
+		Ecosystem:   "maven",
+		Name:        d.name,
+		Version:     d.version,
+		Source:      d.source,
+		Direct:      true,
+		Dev:         gradleDevConfiguration(d.configuration),
+		NativeScope: d.configuration,
+	}
+}
+
+func containsRef(refs []PackageRef, name string) bool {
+	for _, r := range refs {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// gradleSubprojectDirs returns the absolute directories of the included
+// subprojects, which DiscoverGradle reports itself.
+func gradleSubprojectDirs(dir string) []string {
+	var dirs []string
+	for _, sub := range gradleSubprojects(dir) {
+		dirs = append(dirs, filepath.Join(dir, filepath.FromSlash(sub)))
+	}
+	return dirs
+}
+
+// gradleSubprojects returns the directories of the projects included from
+// settings.gradle(.kts), e.g. include(":app", ":libs:core") -> app, libs/core.
+func gradleSubprojects(dir string) []string {
+	var subs []string
+	for _, f := range []string{"settings.gradle", "settings.gradle.kts"} {
+		b, err := os.ReadFile(filepath.Join(dir, f))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, "include") {
+				continue
+			}
+			for _, m := range gradleQuotedRe.FindAllStringSubmatch(line, -1) {
+				p := strings.ReplaceAll(strings.Trim(m[1], ":"), ":", "/")
+				if p != "" && !containsString(subs, p) {
+					subs = append(subs, p)
+				}
+			}
+		}
+	}
+	return subs
+}
+
+// readGradleLockfiles reads gradle.lockfile and buildscript-gradle.lockfile
+// (Gradle 6+), falling back to the per-configuration files under
+// gradle/dependency-locks used by older versions.
+func readGradleLockfiles(root, pdir string) []PackageRef {
+	configs := make(map[string][]string) // g:a:v -> configurations
+	sources := make(map[string]string)
+	var order []string
+
+	add := func(coords, source string, cfgs []string) {
+		if strings.Count(coords, ":") < 2 {
+			return
+		}
+		if _, ok := configs[coords]; !ok {
+			order = append(order, coords)
+			sources[coords] = source
+		}
+		for _, c := range cfgs {
+			if !containsString(configs[coords], c) {
+				configs[coords] = append(configs[coords], c)
+			}
+		}
+	}
+	read := func(path string, each func(line string)) bool {
+		f, err := os.Open(path)
+		if err != nil {
+			return false
+		}
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				each(line)
+			}
+		}
+		return true
+	}
+
+	found := false
+	for _, name := range []string{"gradle.lockfile", "buildscript-gradle.lockfile"} {
+		path := filepath.Join(pdir, name)
+		source := relSource(root, path)
+		found = read(path, func(line string) {
+			coords, cfgs, ok := strings.Cut(line, "=")
+			if !ok || coords == "empty" {
+				return
+			}
+			add(coords, source, strings.Split(cfgs, ","))
+		}) || found
+	}
+	if !found {
+		legacy, _ := filepath.Glob(filepath.Join(pdir, "gradle", "dependency-locks", "*.lockfile"))
+		for _, path := range legacy {
+			source := relSource(root, path)
+			cfg := strings.TrimSuffix(filepath.Base(path), ".lockfile")
+			read(path, func(line string) { add(line, source, []string{cfg}) })
+		}
+	}
+
+	var refs []PackageRef
+	for _, coords := range order {
+		i := strings.LastIndex(coords, ":")
+		cfgs := configs[coords]
+		sort.Strings(cfgs)
+		// buildscript dependencies are plugins and build tooling
+		dev := filepath.Base(sources[coords]) == "buildscript-gradle.lockfile"
+		if !dev {
+			dev = true
+			for _, c := range cfgs {
+				dev = dev && gradleDevConfiguration(c)
+			}
+		}
+		refs = append(refs, PackageRef{
+			Ecosystem:   "maven",
+			Name:        coords[:i],
+			Version:     coords[i+1:],
+			Source:      sources[coords],
+			Dev:         dev,
+			NativeScope: strings.Join(cfgs, ","),
+		})
+	}
+	return refs
+}
+
+// readGradleBuild extracts dependency declarations from a project's build
+// script. This is pattern matching, not evaluation: declarations built from
+// arbitrary expressions are missed.
+func readGradleBuild(root, pdir string, props map[string]string, catalog map[string]gradleDecl, used map[string]bool) []gradleDecl {
+	var path string
+	var content string
+	for _, f := range []string{"build.gradle.kts", "build.gradle"} {
+		if b, err := os.ReadFile(filepath.Join(pdir, f)); err == nil {
+			path, content = filepath.Join(pdir, f), string(b)
+			break
+		}
+	}
+	if path == "" {
+		return nil
+	}
+	source := relSource(root, path)
+
+	vars := make(map[string]string, len(props))
+	for k, v := range props {
+		vars[k] = v
+	}
+	for _, m := range gradleVarRe.FindAllStringSubmatch(content, -1) {
+		vars[m[1]] = m[2]
+	}
+	expand := func(s string) string {
+		return gradleInterpRe.ReplaceAllStringFunc(s, func(ref string) string {
+			name := strings.Trim(ref, "${}")
+			if v, ok := vars[name]; ok {
+				return v
+			}
+			return ref
+		})
+	}
+
+	var out []gradleDecl
+	for _, m := range gradleDeclRe.FindAllStringSubmatch(content, -1) {
+		cfg := m[1]
+		if m[3] != "" {
+			for _, d := range catalogLookup(catalog, strings.TrimSuffix(m[3], ".get"), used) {
+				d.configuration, d.source = cfg, source
+				out = append(out, d)
+			}
+			continue
+		}
+		coords := expand(m[2])
+		if strings.ContainsAny(coords, "/\\") {
+			continue // URLs and file paths
+		}
+		parts := strings.Split(coords, ":")
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			continue
+		}
+		d := gradleDecl{name: parts[0] + ":" + parts[1], configuration: cfg, source: source}
+		if len(parts) > 2 {
+			d.version = strings.SplitN(parts[2], "@", 2)[0]
+		}
+		out = append(out, d)
+	}
+	for _, m := range gradleMapDeclRe.FindAllStringSubmatch(content, -1) {
+		out = append(out, gradleDecl{
+			name:          expand(m[2]) + ":" + expand(m[3]),
+			version:       expand(m[4]),
+			configuration: m[1],
+			source:        source,
+		})
+	}
+	return out
+}
+
+// catalogLookup resolves a type-safe accessor such as libs.jackson.databind
+// or libs.bundles.testing against the catalog aliases.
+func catalogLookup(catalog map[string]gradleDecl, accessor string, used map[string]bool) []gradleDecl {
+	path := strings.TrimPrefix(accessor, "libs.")
+	if strings.HasPrefix(path, "versions.") || strings.HasPrefix(path, "plugins.") {
+		return nil
+	}
+	if bundle, ok := strings.CutPrefix(path, "bundles."); ok {
+		d, found := catalog["bundles."+gradleAliasKey(bundle)]
+		if !found {
+			return nil
+		}
+		var out []gradleDecl
+		for _, alias := range strings.Split(d.name, ",") {
+			if lib, ok := catalog[alias]; ok {
+				used[alias] = true
+				out = append(out, lib)
+			}
+		}
+		return out
+	}
+	key := gradleAliasKey(path)
+	d, ok := catalog[key]
+	if !ok {
+		return nil
+	}
+	used[key] = true
+	return []gradleDecl{d}
+}
+
+// gradleAliasKey normalises catalog aliases: "jackson-databind",
+// "jackson_databind" and the accessor "jackson.databind" are the same library.
+func gradleAliasKey(alias string) string {
+	return gradleAliasSep.ReplaceAllString(strings.ToLower(alias), ".")
+}
+
+// readVersionCatalog returns the [libraries] of a version catalog keyed by
+// normalised alias. Bundles are stored under "bundles.<alias>" with the
+// member aliases comma-separated in name.
+func readVersionCatalog(path string) map[string]gradleDecl {
+	catalog := make(map[string]gradleDecl)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return catalog
+	}
+	var cat struct {
+		Versions  map[string]any      `toml:"versions"`
+		Libraries map[string]any      `toml:"libraries"`
+		Bundles   map[string][]string `toml:"bundles"`
+	}
+	if toml.Unmarshal(b, &cat) != nil {
+		return catalog
+	}
+	source := filepath.ToSlash(filepath.Join("gradle", filepath.Base(path)))
+
+	versionOf := func(v any) string {
+		switch v := v.(type) {
+		case string:
+			return v
+		case map[string]any:
+			if ref, ok := v["ref"].(string); ok {
+				if s, ok := cat.Versions[ref].(string); ok {
+					return s
+				}
+				if t, ok := cat.Versions[ref].(map[string]any); ok {
+					v = t
+				}
+			}
+			for _, k := range []string{"strictly", "require", "prefer"} {
+				if s, ok := v[k].(string); ok {
+					return s
+				}
+			}
+		}
+		return ""
+	}
+
+	for alias, lib := range cat.Libraries {
+		d := gradleDecl{source: source}
+		switch lib := lib.(type) {
+		case string:
+			parts := strings.Split(lib, ":")
+			if len(parts) < 2 {
+				continue
+			}
+			d.name = parts[0] + ":" + parts[1]
+			if len(parts) > 2 {
+				d.version = parts[2]
+			}
+		case map[string]any:
+			if module, ok := lib["module"].(string); ok {
+				d.name = module
+			} else {
+				group, _ := lib["group"].(string)
+				name, _ := lib["name"].(string)
+				d.name = group + ":" + name
+			}
+			d.version = versionOf(lib["version"]) // version.ref = "x" decodes as version = {ref = "x"}
+		default:
+			continue
+		}
+		catalog[gradleAliasKey(alias)] = d
+	}
+	for alias, members := range cat.Bundles {
+		keys := make([]string, 0, len(members))
+		for _, m := range members {
+			keys = append(keys, gradleAliasKey(m))
+		}
+		catalog["bundles."+gradleAliasKey(alias)] = gradleDecl{name: strings.Join(keys, ",")}
+	}
+	return catalog
+}
+
+func readGradleProperties(path string) map[string]string {
+	props := make(map[string]string)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return props
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, "="); ok {
+			props[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return props
+}
+
+// gradleDevConfiguration reports whether a configuration only serves tests
+// or the build itself.
+func gradleDevConfiguration(cfg string) bool {
+	lower := strings.ToLower(cfg)
+	return strings.HasPrefix(lower, "test") || strings.HasPrefix(lower, "androidtest") || lower == "classpath"
+}
+
+func relSource(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,105 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+type helmDependency struct {
+	Name       string   `yaml:"name"`
+	Version    string   `yaml:"version"`
+	Repository string   `yaml:"repository"`
+	Condition  string   `yaml:"condition"`
+	Tags       []string `yaml:"tags"`
+}
+
+type helmDependencies struct {
+	Dependencies []helmDependency `yaml:"dependencies"`
+}
+
+// DiscoverHelm lists the chart dependencies of a Helm chart: those declared
+// in Chart.yaml, or requirements.yaml for apiVersion v1 charts, at the
+// versions Chart.lock (requirements.lock) resolved them to. A dependency
+// that is not locked to an exact version is reported with Unpinned set;
+// conditional or tagged dependencies are Optional.
+func DiscoverHelm(dir string) []PackageRef {
+	declFile, lockFile := "Chart.yaml", "Chart.lock"
+	declared := readHelmDependencies(filepath.Join(dir, declFile))
+	if len(declared) == 0 {
+		// a v1 chart's Chart.yaml has no dependencies key at all
+		if req := readHelmDependencies(filepath.Join(dir, "requirements.yaml")); req != nil {
+			declFile, lockFile = "requirements.yaml", "requirements.lock"
+			declared = req
+		}
+	}
+	locked := readHelmDependencies(filepath.Join(dir, lockFile))
+
+	var refs []PackageRef
+	for _, d := range declared {
+		if d.Name == "" {
+			continue
+		}
+		ref := PackageRef{
+			Ecosystem:  "helm",
+			Name:       d.Name,
+			Version:    d.Version,
+			Source:     declFile,
+			Repository: d.Repository,
+			Direct:     true,
+			Optional:   d.Condition != "" || len(d.Tags) > 0,
+			Groups:     d.Tags,
+		}
+		if strings.HasPrefix(d.Repository, "file://") {
+			ref.Kind = "path"
+			refs = append(refs, ref)
+			continue
+		}
+		for _, l := range locked {
+			// the lock file records the URL behind a repository alias ("@bitnami")
+			if l.Name == d.Name && (l.Repository == d.Repository || strings.HasPrefix(d.Repository, "@") || strings.HasPrefix(d.Repository, "alias:")) {
+				ref.Version = l.Version
+				ref.Source = lockFile
+				break
+			}
+		}
+		if ref.Source == declFile {
+			switch {
+			case locked == nil && helmExactVersion(d.Version):
+				// an exact version needs no lock file
+			case locked == nil:
+				ref.Unpinned = "version range " + d.Version + " without " + lockFile
+			default:
+				ref.Unpinned = "not in " + lockFile
+			}
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// readHelmDependencies returns the dependencies listed in a Chart.yaml,
+// requirements.yaml or their lock files, or nil if the file is missing.
+func readHelmDependencies(path string) []helmDependency {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var f helmDependencies
+	if yaml.Unmarshal(b, &f) != nil {
+		return nil
+	}
+	if f.Dependencies == nil {
+		return []helmDependency{}
+	}
+	return f.Dependencies
+}
+
+// helmExactVersion reports whether a Chart.yaml version is a plain semver
+// version rather than a range ("^1.2.0", "1.x", ">= 1.0").
+func helmExactVersion(v string) bool {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	return v != "" && !strings.ContainsAny(v, "^~<>=|*xX ,")
+}
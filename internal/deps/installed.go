@@ -0,0 +1,144 @@
+package deps
+
+import (
+	"path"
+	"strings"
+)
+
+// reconcileInstalled merges installed packages into the collection. An
+// installed package is compared with the lockfile entries of its ecosystem
+// in the same subproject, or in one containing it or contained by it:
+//   - a matching entry (same lockfile path for npm, otherwise same name and
+//     version) gets the installed Location and nothing is added;
+//   - a version the lockfile does not pin is added with Mismatch set, and so
+//     is a name it does not list when the data is a full resolution (see
+//     fullResolution);
+//   - otherwise, or without any related lockfile data, the package is
+//     added as found.
+func (c *Collection) reconcileInstalled(installed []PackageRef) {
+	locked := make(map[string]int) // lockfile entries per ecosystem, before anything is added
+	for _, e := range *c {
+		locked[e.Ecosystem.ID] = len(e.Packages)
+	}
+
+	for _, inst := range installed {
+		e := c.entry(inst.Ecosystem)
+		lock := e.Packages[:locked[inst.Ecosystem]]
+
+		related, resolved, candidates := false, false, []int{}
+		for i, p := range lock {
+			if !relatedSubprojects(p.Subproject, inst.Subproject) {
+				continue
+			}
+			related = true
+			resolved = resolved || fullResolution(p)
+			if installedKey(p) == installedKey(inst) {
+				candidates = append(candidates, i)
+			}
+		}
+		if !related {
+			e.Packages = append(e.Packages, inst)
+			continue
+		}
+		if len(candidates) == 0 {
+			// direct requirements alone say nothing about transitive packages
+			if resolved {
+				inst.Mismatch = joinMismatch(inst.Mismatch, "not in lockfile")
+			}
+			e.Packages = append(e.Packages, inst)
+			continue
+		}
+
+		match := -1
+		for _, i := range candidates {
+			if p := lock[i]; p.Path != "" && inst.Path != "" && joinSubproject(orRoot(p.Subproject), p.Path) == inst.Location {
+				match = i // the lockfile entry for this very directory
+				break
+			}
+		}
+		if match < 0 {
+			for _, i := range candidates {
+				if lock[i].Version == inst.Version && (match < 0 || lock[match].Location != "") {
+					match = i
+				}
+			}
+		}
+		var pinned []string
+		for _, i := range candidates {
+			if v := lock[i].Version; pinnedVersion(v) && !containsString(pinned, v) {
+				pinned = append(pinned, v)
+			}
+		}
+		if match < 0 && len(pinned) == 0 {
+			match = candidates[0] // only ranges in the lockfile, nothing to compare
+		}
+
+		switch {
+		case match >= 0 && (lock[match].Version == inst.Version || !pinnedVersion(lock[match].Version)):
+			if p := &e.Packages[match]; p.Location == "" {
+				p.Location = inst.Location
+				p.Mismatch = joinMismatch(p.Mismatch, inst.Mismatch)
+			} else {
+				e.Packages = append(e.Packages, inst) // another copy of the same version
+			}
+		case match >= 0:
+			inst.Mismatch = joinMismatch(inst.Mismatch, "lockfile has "+lock[match].Version)
+			e.Packages = append(e.Packages, inst)
+		default:
+			inst.Mismatch = joinMismatch(inst.Mismatch, "lockfile has "+strings.Join(pinned, ", "))
+			e.Packages = append(e.Packages, inst)
+		}
+	}
+}
+
+// fullResolution reports whether a package comes from data that lists the
+// whole dependency tree: a lockfile, or a manifest whose transitive
+// dependencies were resolved (a pom.xml read with a local repository).
+func fullResolution(p PackageRef) bool {
+	switch name := path.Base(p.Source); name {
+	case "package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "poetry.lock", "uv.lock", "Pipfile.lock":
+		return true
+	default:
+		if strings.HasSuffix(name, ".lockfile") {
+			return true // Gradle dependency locking
+		}
+	}
+	return !p.Direct
+}
+
+// installedKey is the name installed packages are matched by.
+func installedKey(p PackageRef) string {
+	if p.Ecosystem == "python" {
+		return normalizePyName(p.Name)
+	}
+	return p.Name
+}
+
+// pinnedVersion reports whether a lockfile version is exact rather than a
+// requirement range (">=1.0", "^2", "[1.0,2.0)").
+func pinnedVersion(v string) bool {
+	return v != "" && !strings.ContainsAny(v[:1], "<>=!~^[(") && !strings.ContainsAny(v, ", *")
+}
+
+// relatedSubprojects reports whether one subproject is the other or
+// contains it.
+func relatedSubprojects(a, b string) bool {
+	if a == "" || b == "" || a == b {
+		return true
+	}
+	return strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+func orRoot(subproject string) string {
+	if subproject == "" {
+		return "."
+	}
+	return subproject
+}
+
+func joinMismatch(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+	return a + "; " + b
+}
@@ -0,0 +1,128 @@
+package deps
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// jarBuildDirs are where Maven and Gradle put the archives they build.
+var jarBuildDirs = []string{"target", "build/libs"}
+
+// Limits on the nested archives read into memory, which may come from an
+// untrusted image: an EAR holding WARs holding JARs is three levels deep.
+const (
+	maxNestedJarSize  = 256 << 20 // 256MB
+	maxNestedJarDepth = 4
+)
+
+// DiscoverJars lists the Maven artifacts packaged in the JAR, WAR and EAR
+// files in dir and its build output directories, from the
+// META-INF/maven/**/pom.properties of each archive and of the archives
+// nested inside it (WEB-INF/lib, BOOT-INF/lib, EAR modules). Location is
+// the archive path, with "!/" separating nesting levels. The artifact of
+// an archive the project built itself is not reported, only what it
+// bundles.
+func DiscoverJars(dir string) []PackageRef {
+	var refs []PackageRef
+	for _, sub := range append([]string{"."}, jarBuildDirs...) {
+		for _, ext := range []string{"*.jar", "*.war", "*.ear"} {
+			matches, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(sub), ext))
+			for _, archive := range matches {
+				zr, err := zip.OpenReader(archive)
+				if err != nil {
+					continue
+				}
+				built := ""
+				if sub != "." {
+					built = filepath.Base(archive)
+				}
+				refs = append(refs, readJar(&zr.Reader, relSource(dir, archive), built, 0)...)
+				zr.Close()
+			}
+		}
+	}
+	sort.SliceStable(refs, func(i, j int) bool { return refs[i].Location < refs[j].Location })
+	return refs
+}
+
+// jarCovers returns the build output directories DiscoverJars already
+// looks into.
+func jarCovers(dir string) []string {
+	var out []string
+	for _, sub := range jarBuildDirs {
+		out = append(out, filepath.Join(dir, filepath.FromSlash(sub)))
+	}
+	return out
+}
+
+// readJar reads the pom.properties of an archive and recurses into the
+// archives it contains, up to maxNestedJarDepth levels and skipping those
+// larger than maxNestedJarSize. When built names the file of an archive
+// the project built, the artifact the file is named after is skipped.
+func readJar(zr *zip.Reader, location, built string, depth int) []PackageRef {
+	var refs []PackageRef
+	for _, f := range zr.File {
+		name := f.Name
+		switch {
+		case strings.HasPrefix(name, "META-INF/maven/") && path.Base(name) == "pom.properties":
+			props := readZipProperties(f)
+			a := props["artifactId"]
+			if props["groupId"] == "" || a == "" || built != "" && (strings.HasPrefix(built, a+"-") || strings.HasPrefix(built, a+".")) {
+				continue
+			}
+			refs = append(refs, PackageRef{
+				Ecosystem: "maven",
+				Name:      props["groupId"] + ":" + props["artifactId"],
+				Version:   props["version"],
+				Source:    location + "!/" + name,
+				Location:  location,
+			})
+		case strings.HasSuffix(name, ".jar") || strings.HasSuffix(name, ".war"):
+			if depth >= maxNestedJarDepth || f.UncompressedSize64 > maxNestedJarSize {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				continue
+			}
+			// the header's size may lie, so the read is capped as well
+			b, err := io.ReadAll(io.LimitReader(rc, maxNestedJarSize+1))
+			rc.Close()
+			if err != nil || len(b) > maxNestedJarSize {
+				continue
+			}
+			nested, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+			if err != nil {
+				continue
+			}
+			refs = append(refs, readJar(nested, location+"!/"+name, "", depth+1)...)
+		}
+	}
+	return refs
+}
+
+func readZipProperties(f *zip.File) map[string]string {
+	props := make(map[string]string)
+	rc, err := f.Open()
+	if err != nil {
+		return props
+	}
+	defer rc.Close()
+	sc := bufio.NewScanner(rc)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, "="); ok {
+			props[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return props
+}
@@ -0,0 +1,149 @@
+package deps
+
+import "sort"
+
+// lockGraph holds the packages of a lockfile whose dependencies point at other
+// entries by key (yarn descriptors, pnpm package ids). Several keys may map
+// to the same node.
+type lockGraph struct {
+	nodes map[string]*lockNode
+}
+
+type lockNode struct {
+	ref  PackageRef
+	deps []lockEdge
+}
+
+type lockEdge struct {
+	key      string
+	optional bool
+}
+
+func newLockGraph() *lockGraph {
+	return &lockGraph{nodes: make(map[string]*lockNode)}
+}
+
+func (g *lockGraph) add(keys []string, n *lockNode) {
+	for _, k := range keys {
+		g.nodes[k] = n
+	}
+}
+
+// refs resolves the edges of the graph into PackageRefs. prodRoots and
+// devRoots are the keys the project depends on directly; packages only
+// reachable from devRoots are flagged Dev, and packages only reached through
+// optional edges are flagged Optional. Flags already set on a node are kept.
+func (g *lockGraph) refs(prodRoots, devRoots []string) []PackageRef {
+	var nodes []*lockNode
+	seenNode := make(map[*lockNode]bool)
+	keys := make([]string, 0, len(g.nodes))
+	for k := range g.nodes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if n := g.nodes[k]; !seenNode[n] {
+			seenNode[n] = true
+			nodes = append(nodes, n)
+		}
+	}
+
+	hardIn := make(map[*lockNode]bool)
+	optIn := make(map[*lockNode]bool)
+	for _, n := range nodes {
+		for _, e := range n.deps {
+			child := g.nodes[e.key]
+			if child == nil {
+				continue
+			}
+			if e.optional {
+				optIn[child] = true
+			} else {
+				hardIn[child] = true
+			}
+			if !containsString(child.ref.Parents, n.ref.Name) {
+				child.ref.Parents = append(child.ref.Parents, n.ref.Name)
+			}
+		}
+	}
+
+	for _, k := range append(append([]string{}, prodRoots...), devRoots...) {
+		if n := g.nodes[k]; n != nil {
+			n.ref.Direct = true
+			hardIn[n] = true
+		}
+	}
+
+	prod := g.reachable(prodRoots)
+	dev := g.reachable(devRoots)
+	for _, n := range nodes {
+		// nothing in the lockfile requires it, so the project itself must
+		n.ref.Direct = n.ref.Direct || len(n.ref.Parents) == 0
+		n.ref.Dev = n.ref.Dev || (!prod[n] && dev[n])
+		n.ref.Optional = n.ref.Optional || (optIn[n] && !hardIn[n])
+	}
+
+	var refs []PackageRef
+	seen := make(map[string]int)
+	for _, n := range nodes {
+		id := n.ref.Name + "@" + n.ref.Version
+		if i, ok := seen[id]; ok {
+			merged := &refs[i]
+			merged.Direct = merged.Direct || n.ref.Direct
+			merged.Dev = merged.Dev && n.ref.Dev
+			merged.Optional = merged.Optional && n.ref.Optional
+			for _, parent := range n.ref.Parents {
+				if !containsString(merged.Parents, parent) {
+					merged.Parents = append(merged.Parents, parent)
+				}
+			}
+			continue
+		}
+		seen[id] = len(refs)
+		refs = append(refs, n.ref)
+	}
+	return refs
+}
+
+func (g *lockGraph) reachable(roots []string) map[*lockNode]bool {
+	visited := make(map[*lockNode]bool)
+	var queue []*lockNode
+	for _, k := range roots {
+		if n := g.nodes[k]; n != nil && !visited[n] {
+			visited[n] = true
+			queue = append(queue, n)
+		}
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, e := range n.deps {
+			if child := g.nodes[e.key]; child != nil && !visited[child] {
+				visited[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+	return visited
+}
+
+// subgraph copies the nodes reachable from roots into a new graph, so that
+// refs only reports (and links) what those roots actually pull in.
+func (g *lockGraph) subgraph(roots []string) *lockGraph {
+	reach := g.reachable(roots)
+	copies := make(map[*lockNode]*lockNode)
+	sub := newLockGraph()
+	for k, n := range g.nodes {
+		if !reach[n] {
+			continue
+		}
+		c, ok := copies[n]
+		if !ok {
+			c = &lockNode{ref: n.ref, deps: n.deps}
+			c.ref.Parents = nil
+			copies[n] = c
+		}
+		sub.nodes[k] = c
+	}
+	return sub
+}
@@ -4,33 +4,395 @@ import (
 	"encoding/xml"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
-func DiscoverMaven(dir string) []PackageRef {
-	// detect pom.xml only (real parsing can be added later)
-	p := filepath.Join(dir, "pom.xml")
-	b, err := os.ReadFile(p)
-	if err != nil {
-		return nil
+type pomExclusion struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+}
+
+type pomDependency struct {
+	GroupID    string         `xml:"groupId"`
+	ArtifactID string         `xml:"artifactId"`
+	Version    string         `xml:"version"`
+	Type       string         `xml:"type"`
+	Classifier string         `xml:"classifier"`
+	Scope      string         `xml:"scope"`
+	Optional   string         `xml:"optional"`
+	Exclusions []pomExclusion `xml:"exclusions>exclusion"`
+}
+
+func (d pomDependency) key() string {
+	return d.GroupID + ":" + d.ArtifactID
+}
+
+type pomParent struct {
+	GroupID      string  `xml:"groupId"`
+	ArtifactID   string  `xml:"artifactId"`
+	Version      string  `xml:"version"`
+	RelativePath *string `xml:"relativePath"` // nil means the default ../pom.xml
+}
+
+// xmlProperties collects free-form child elements (POM <properties>, MSBuild
+// <PropertyGroup>) into a map.
+type xmlProperties map[string]string
+
+func (p *xmlProperties) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*p = xmlProperties{}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			var v string
+			if err := d.DecodeElement(&v, &t); err != nil {
+				return err
+			}
+			(*p)[t.Name.Local] = strings.TrimSpace(v)
+		case xml.EndElement:
+			return nil
+		}
 	}
-	// naive extraction of <groupId>, <artifactId>, <version> blocks (not accurate for complex poms)
-	type dep struct {
-		GroupID    string `xml:"groupId"`
-		ArtifactID string `xml:"artifactId"`
-		Version    string `xml:"version"`
+}
+
+type pomFile struct {
+	GroupID      string          `xml:"groupId"`
+	ArtifactID   string          `xml:"artifactId"`
+	Version      string          `xml:"version"`
+	Parent       *pomParent      `xml:"parent"`
+	Properties   xmlProperties   `xml:"properties"`
+	Managed      []pomDependency `xml:"dependencyManagement>dependencies>dependency"`
+	Dependencies []pomDependency `xml:"dependencies>dependency"`
+	Modules      []string        `xml:"modules>module"`
+}
+
+// pomModel is the effective model of a POM: parent inheritance applied,
+// properties interpolated and dependencyManagement (including imported
+// BOMs) folded into the dependency list.
+type pomModel struct {
+	path       string
+	groupID    string
+	artifactID string
+	version    string
+	props      map[string]string
+	managed    map[string]pomDependency
+	deps       []pomDependency
+	modules    []string
+}
+
+// mavenResolver builds effective POM models. POMs referenced by coordinates
+// (parents without a usable relativePath, imported BOMs, dependencies) are
+// looked up among the reactor modules and then in the local repository.
+type mavenResolver struct {
+	localRepo string
+	models    map[string]*pomModel
+	reactor   map[string]string // groupId:artifactId:version -> pom path
+	resolved  map[string]bool   // guards against parent/import cycles
+}
+
+func newMavenResolver(localRepo string) *mavenResolver {
+	return &mavenResolver{
+		localRepo: localRepo,
+		models:    make(map[string]*pomModel),
+		reactor:   make(map[string]string),
+		resolved:  make(map[string]bool),
 	}
-	type pom struct {
-		Deps []dep `xml:"dependencies>dependency"`
+}
+
+// DefaultMavenRepo returns the local repository Maven itself would use,
+// ~/.m2/repository, or "" when the home directory is unknown.
+func DefaultMavenRepo() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
 	}
-	var x pom
-	if xml.Unmarshal(b, &x) != nil {
-		return []PackageRef{{Ecosystem: "maven", Name: "(see pom.xml)", Source: "pom.xml"}}
+	return filepath.Join(home, ".m2", "repository")
+}
+
+// DiscoverMaven resolves the dependencies of pom.xml and its modules. When
+// localRepo is set, transitive dependencies are resolved offline from the
+// POMs found there.
+func DiscoverMaven(dir, localRepo string) []PackageRef {
+	root := filepath.Join(dir, "pom.xml")
+	if _, err := os.Stat(root); err != nil {
+		return nil
 	}
+
+	r := newMavenResolver(localRepo)
+	var poms []string
+	r.indexReactor(root, &poms)
+
 	var refs []PackageRef
-	for _, d := range x.Deps {
-		n := strings.TrimSpace(d.GroupID + ":" + d.ArtifactID)
-		refs = append(refs, PackageRef{Ecosystem: "maven", Name: n, Version: strings.TrimSpace(d.Version), Source: "pom.xml"})
+	for _, p := range poms {
+		m := r.model(p)
+		if m == nil {
+			continue
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		subproject := filepath.ToSlash(filepath.Dir(rel))
+		if subproject == "." {
+			subproject = ""
+		}
+		for _, ref := range r.transitive(m, rel) {
+			ref.Subproject = subproject
+			refs = append(refs, ref)
+		}
 	}
 	return refs
 }
+
+// mavenModuleDirs returns the directories of the reactor modules below the
+// POM in dir, which DiscoverMaven reports itself.
+func mavenModuleDirs(dir string) []string {
+	var poms []string
+	newMavenResolver("").indexReactor(filepath.Join(dir, "pom.xml"), &poms)
+	var dirs []string
+	for _, p := range poms {
+		if d := filepath.Dir(p); d != filepath.Clean(dir) {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+// indexReactor walks <modules> from the given POM, recording each module's
+// coordinates so they can be resolved without a repository.
+func (r *mavenResolver) indexReactor(path string, out *[]string) {
+	path = filepath.Clean(path)
+	if containsString(*out, path) {
+		return
+	}
+	raw, err := readPom(path)
+	if err != nil {
+		return
+	}
+	*out = append(*out, path)
+
+	g, v := raw.GroupID, raw.Version
+	if raw.Parent != nil {
+		if g == "" {
+			g = raw.Parent.GroupID
+		}
+		if v == "" {
+			v = raw.Parent.Version
+		}
+	}
+	r.reactor[g+":"+raw.ArtifactID+":"+v] = path
+
+	for _, mod := range raw.Modules {
+		mp := filepath.Join(filepath.Dir(path), strings.TrimSpace(mod))
+		if !strings.HasSuffix(mp, ".xml") {
+			mp = filepath.Join(mp, "pom.xml")
+		}
+		r.indexReactor(mp, out)
+	}
+}
+
+// locate finds the POM file for the given coordinates.
+func (r *mavenResolver) locate(groupID, artifactID, version string) string {
+	if p, ok := r.reactor[groupID+":"+artifactID+":"+version]; ok {
+		return p
+	}
+	if r.localRepo == "" || groupID == "" || artifactID == "" || version == "" {
+		return ""
+	}
+	p := filepath.Join(r.localRepo, filepath.FromSlash(strings.ReplaceAll(groupID, ".", "/")),
+		artifactID, version, artifactID+"-"+version+".pom")
+	if _, err := os.Stat(p); err != nil {
+		return ""
+	}
+	return p
+}
+
+func (r *mavenResolver) model(path string) *pomModel {
+	path = filepath.Clean(path)
+	if m, ok := r.models[path]; ok {
+		return m
+	}
+	if r.resolved[path] {
+		return nil
+	}
+	r.resolved[path] = true
+
+	raw, err := readPom(path)
+	if err != nil {
+		return nil
+	}
+
+	m := &pomModel{
+		path:       path,
+		groupID:    raw.GroupID,
+		artifactID: raw.ArtifactID,
+		version:    raw.Version,
+		props:      make(map[string]string),
+		managed:    make(map[string]pomDependency),
+		modules:    raw.Modules,
+	}
+
+	if raw.Parent != nil {
+		if parent := r.parentModel(path, raw.Parent); parent != nil {
+			for k, v := range parent.props {
+				m.props[k] = v
+			}
+			for k, v := range parent.managed {
+				m.managed[k] = v
+			}
+			m.deps = append(m.deps, parent.deps...)
+			m.props["project.parent.groupId"] = parent.groupID
+			m.props["project.parent.version"] = parent.version
+		}
+		if m.groupID == "" {
+			m.groupID = raw.Parent.GroupID
+		}
+		if m.version == "" {
+			m.version = raw.Parent.Version
+		}
+	}
+
+	for k, v := range raw.Properties {
+		m.props[k] = v
+	}
+	for _, prefix := range []string{"project.", "pom.", ""} {
+		m.props[prefix+"groupId"] = m.groupID
+		m.props[prefix+"artifactId"] = m.artifactID
+		m.props[prefix+"version"] = m.version
+	}
+	m.props["project.basedir"] = filepath.Dir(path)
+
+	// own dependencyManagement overrides the parent's; imported BOMs only
+	// fill in what is not managed explicitly
+	var imports []pomDependency
+	for _, d := range raw.Managed {
+		d = m.interpolateDep(d)
+		if d.Scope == "import" && (d.Type == "pom" || d.Type == "") {
+			imports = append(imports, d)
+			continue
+		}
+		m.managed[d.key()] = d
+	}
+	for _, imp := range imports {
+		bom := r.model(r.locate(imp.GroupID, imp.ArtifactID, imp.Version))
+		if bom == nil {
+			continue
+		}
+		for k, d := range bom.managed {
+			if _, ok := m.managed[k]; !ok {
+				m.managed[k] = d
+			}
+		}
+	}
+
+	for _, d := range raw.Dependencies {
+		m.deps = append(m.deps, m.interpolateDep(d))
+	}
+	for i, d := range m.deps {
+		m.deps[i] = m.applyManagement(d)
+	}
+	m.deps = dedupePomDeps(m.deps)
+
+	r.models[path] = m
+	return m
+}
+
+// parentModel resolves <parent> through its relativePath (default ../pom.xml)
+// when the POM found there matches, and by coordinates otherwise.
+func (r *mavenResolver) parentModel(child string, p *pomParent) *pomModel {
+	rel := "../pom.xml"
+	if p.RelativePath != nil {
+		rel = strings.TrimSpace(*p.RelativePath)
+	}
+	if rel != "" {
+		pp := filepath.Join(filepath.Dir(child), rel)
+		if !strings.HasSuffix(pp, ".xml") {
+			pp = filepath.Join(pp, "pom.xml")
+		}
+		if m := r.model(pp); m != nil && m.groupID == p.GroupID && m.artifactID == p.ArtifactID {
+			return m
+		}
+	}
+	return r.model(r.locate(p.GroupID, p.ArtifactID, p.Version))
+}
+
+func (m *pomModel) applyManagement(d pomDependency) pomDependency {
+	md, ok := m.managed[d.key()]
+	if !ok {
+		return d
+	}
+	if d.Version == "" {
+		d.Version = md.Version
+	}
+	if d.Scope == "" {
+		d.Scope = md.Scope
+	}
+	if d.Optional == "" {
+		d.Optional = md.Optional
+	}
+	d.Exclusions = append(d.Exclusions, md.Exclusions...)
+	return d
+}
+
+var pomPropRe = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// interpolate expands ${...} references, including properties that refer
+// to other properties. Unknown references are left in place.
+func (m *pomModel) interpolate(s string) string {
+	s = strings.TrimSpace(s)
+	for i := 0; i < 10 && strings.Contains(s, "${"); i++ {
+		next := pomPropRe.ReplaceAllStringFunc(s, func(ref string) string {
+			if v, ok := m.props[ref[2:len(ref)-1]]; ok {
+				return v
+			}
+			return ref
+		})
+		if next == s {
+			break
+		}
+		s = next
+	}
+	return s
+}
+
+func (m *pomModel) interpolateDep(d pomDependency) pomDependency {
+	d.GroupID = m.interpolate(d.GroupID)
+	d.ArtifactID = m.interpolate(d.ArtifactID)
+	d.Version = m.interpolate(d.Version)
+	d.Type = m.interpolate(d.Type)
+	d.Classifier = m.interpolate(d.Classifier)
+	d.Scope = m.interpolate(d.Scope)
+	d.Optional = m.interpolate(d.Optional)
+	return d
+}
+
+// dedupePomDeps keeps the last declaration of each groupId:artifactId, so a
+// child POM overrides what it inherited, at the position of the first: the
+// declaration order decides which of two equally near versions Maven picks.
+func dedupePomDeps(deps []pomDependency) []pomDependency {
+	var out []pomDependency
+	index := make(map[string]int)
+	for _, d := range deps {
+		if i, ok := index[d.key()]; ok {
+			out[i] = d
+			continue
+		}
+		index[d.key()] = len(out)
+		out = append(out, d)
+	}
+	return out
+}
+
+func readPom(path string) (*pomFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p pomFile
+	if err := xml.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
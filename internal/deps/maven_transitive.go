@@ -0,0 +1,129 @@
+package deps
+
+// mavenNode is a dependency reached while walking the tree breadth-first.
+type mavenNode struct {
+	dep      pomDependency
+	scope    string
+	optional bool
+	parent   string
+	excluded []pomExclusion // exclusions inherited along the path
+	depth    int
+}
+
+// transitive resolves the dependency tree of a module the way Maven does:
+// breadth-first, the nearest declaration of a groupId:artifactId wins, the
+// module's dependencyManagement pins versions and scopes throughout, and
+// test/provided/optional dependencies of dependencies are not inherited.
+// Without a local repository only the direct dependencies are returned.
+func (r *mavenResolver) transitive(m *pomModel, source string) []PackageRef {
+	var refs []PackageRef
+	seen := map[string]bool{m.groupID + ":" + m.artifactID: true}
+
+	var queue []mavenNode
+	for _, d := range m.deps {
+		scope := d.Scope
+		if scope == "" {
+			scope = "compile"
+		}
+		queue = append(queue, mavenNode{
+			dep:      d,
+			scope:    scope,
+			optional: d.Optional == "true",
+			excluded: d.Exclusions,
+			depth:    1,
+		})
+	}
+
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		key := n.dep.key()
+		if seen[key] || n.dep.Version == "" {
+			continue
+		}
+		seen[key] = true
+
+		_, internal := r.reactor[key+":"+n.dep.Version]
+		if !internal {
+			ref := PackageRef{
+				Ecosystem:   "maven",
+				Name:        key,
+				Version:     n.dep.Version,
+				Source:      source,
+				Direct:      n.depth == 1,
+				Dev:         n.scope == "test",
+				Optional:    n.optional,
+				NativeScope: n.scope,
+			}
+			if n.parent != "" {
+				ref.Parents = []string{n.parent}
+			}
+			refs = append(refs, ref)
+		}
+
+		if n.scope == "system" {
+			continue
+		}
+		dm := r.model(r.locate(n.dep.GroupID, n.dep.ArtifactID, n.dep.Version))
+		if dm == nil {
+			continue
+		}
+		parent := key
+		if internal {
+			parent = n.parent // keep edges pointing at something that is reported
+		}
+		for _, c := range dm.deps {
+			if c.Optional == "true" || mavenExcluded(n.excluded, c) {
+				continue
+			}
+			scope, ok := mavenScope(n.scope, c.Scope)
+			if !ok {
+				continue
+			}
+			// the consuming module's dependencyManagement applies transitively
+			if md, managed := m.managed[c.key()]; managed {
+				if md.Version != "" {
+					c.Version = md.Version
+				}
+				if md.Scope != "" && md.Scope != "import" {
+					if s, ok := mavenScope(n.scope, md.Scope); ok {
+						scope = s
+					}
+				}
+			}
+			queue = append(queue, mavenNode{
+				dep:      c,
+				scope:    scope,
+				optional: n.optional,
+				parent:   parent,
+				excluded: append(append([]pomExclusion{}, n.excluded...), c.Exclusions...),
+				depth:    n.depth + 1,
+			})
+		}
+	}
+	return refs
+}
+
+// mavenScope derives the scope a transitive dependency gets from the scope
+// of the dependency that pulled it in. ok is false when it is not inherited.
+func mavenScope(outer, inner string) (string, bool) {
+	switch inner {
+	case "", "compile":
+		return outer, true
+	case "runtime":
+		if outer == "compile" {
+			return "runtime", true
+		}
+		return outer, true
+	}
+	return "", false
+}
+
+func mavenExcluded(exclusions []pomExclusion, d pomDependency) bool {
+	for _, e := range exclusions {
+		if (e.GroupID == "*" || e.GroupID == d.GroupID) && (e.ArtifactID == "*" || e.ArtifactID == d.ArtifactID) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePom(t *testing.T, path, groupID, artifactID, version string, deps ...[3]string) {
+	t.Helper()
+	body := "<project><groupId>" + groupID + "</groupId><artifactId>" + artifactID + "</artifactId><version>" + version + "</version><dependencies>"
+	for _, d := range deps {
+		body += "<dependency><groupId>" + d[0] + "</groupId><artifactId>" + d[1] + "</artifactId><version>" + d[2] + "</version></dependency>"
+	}
+	body += "</dependencies></project>"
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func repoPom(repo, groupID, artifactID, version string) string {
+	return filepath.Join(repo, filepath.FromSlash(strings.ReplaceAll(groupID, ".", "/")), artifactID, version, artifactID+"-"+version+".pom")
+}
+
+// Of two versions at the same depth, Maven picks the one reached through
+// the dependency declared first, whatever the artifact names.
+func TestMavenMediationFirstDeclarationWins(t *testing.T) {
+	dir, repo := t.TempDir(), t.TempDir()
+	writePom(t, filepath.Join(dir, "pom.xml"), "com.example", "app", "1.0",
+		[3]string{"org.zeta", "zeta", "1.0"},
+		[3]string{"org.alpha", "alpha", "1.0"},
+	)
+	writePom(t, repoPom(repo, "org.zeta", "zeta", "1.0"), "org.zeta", "zeta", "1.0",
+		[3]string{"org.common", "common", "2.0"})
+	writePom(t, repoPom(repo, "org.alpha", "alpha", "1.0"), "org.alpha", "alpha", "1.0",
+		[3]string{"org.common", "common", "1.0"})
+	writePom(t, repoPom(repo, "org.common", "common", "1.0"), "org.common", "common", "1.0")
+	writePom(t, repoPom(repo, "org.common", "common", "2.0"), "org.common", "common", "2.0")
+
+	var common []PackageRef
+	for _, ref := range DiscoverMaven(dir, repo) {
+		if ref.Name == "org.common:common" {
+			common = append(common, ref)
+		}
+	}
+	if len(common) != 1 {
+		t.Fatalf("got %d org.common:common records, want 1: %+v", len(common), common)
+	}
+	if got := common[0]; got.Version != "2.0" || len(got.Parents) != 1 || got.Parents[0] != "org.zeta:zeta" {
+		t.Errorf("got %s via %v, want 2.0 via org.zeta:zeta", got.Version, got.Parents)
+	}
+}
@@ -0,0 +1,143 @@
+package deps
+
+import (
+	"strings"
+)
+
+// SBOMSource is the PackageRef.Source of packages taken from the generated
+// SBOM rather than a file in the project.
+const SBOMSource = "SBOM"
+
+// Merge folds together the records different sources made of the same
+// package, identified by its package URL without version (see PURL).
+// Records from different files of one subproject that agree on version,
+// path and location become one, a version range one file declares is
+// folded into the exact versions another resolves it to, and SBOM records
+// are folded into the manifest records of the same package in any
+// subproject. The merged record lists every source in SeenIn. Where
+// sources report different exact versions, the records keep their own and
+// list the others in VersionConflicts; an SBOM record that matches no
+// version is dropped in favour of the manifest data. Packages only one
+// source saw are left as they are.
+func (c *Collection) Merge() {
+	for i := range *c {
+		(*c)[i].Packages = mergePackages((*c)[i].Packages)
+	}
+}
+
+func mergePackages(pkgs []PackageRef) []PackageRef {
+	var out []PackageRef
+	var sbom []PackageRef
+	exact := make(map[string]int) // identity, subproject, version, path, location -> index in out
+	byID := make(map[string][]int)
+	for _, p := range pkgs {
+		if p.Source == SBOMSource {
+			sbom = append(sbom, p)
+			continue
+		}
+		id := packageIdentity(p)
+		key := strings.Join([]string{id, p.Subproject, p.Version, p.Path, p.Location}, "\x00")
+		if i, ok := exact[key]; ok {
+			if out[i].Source != p.Source {
+				addSeenIn(&out[i], p.Source)
+			}
+			out[i].Scope = WiderScope(out[i].Scope, p.Scope)
+			continue
+		}
+		exact[key] = len(out)
+		byID[id] = append(byID[id], len(out))
+		out = append(out, p)
+	}
+
+	// a range declared in one file is folded into the versions another file
+	// of the subproject resolves it to; exact versions that disagree are
+	// kept and reported
+	dropped := make(map[int]bool)
+	for _, idx := range byID {
+		for _, i := range idx {
+			for _, j := range idx {
+				a, b := out[i], out[j]
+				if a.Subproject != b.Subproject || a.Source == b.Source || a.Version == b.Version || !pinnedVersion(b.Version) {
+					continue
+				}
+				if pinnedVersion(a.Version) {
+					addConflict(&out[i], b.Version+" in "+b.Source)
+				} else {
+					addSeenIn(&out[j], a.Source)
+					out[j].Direct = out[j].Direct || a.Direct
+					out[j].Scope = WiderScope(out[j].Scope, a.Scope)
+					dropped[i] = true
+				}
+			}
+		}
+	}
+	if len(dropped) > 0 {
+		kept := out[:0]
+		for i, p := range out {
+			if !dropped[i] {
+				kept = append(kept, p)
+			}
+		}
+		out = kept
+		byID = make(map[string][]int)
+		for i, p := range out {
+			id := packageIdentity(p)
+			byID[id] = append(byID[id], i)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range sbom {
+		id := packageIdentity(p)
+		if seen[id+"\x00"+p.Version] {
+			continue
+		}
+		seen[id+"\x00"+p.Version] = true
+		idx, ok := byID[id]
+		if !ok {
+			out = append(out, p)
+			continue
+		}
+		matched := false
+		for _, i := range idx {
+			if out[i].Version == p.Version {
+				addSeenIn(&out[i], SBOMSource)
+				matched = true
+			}
+		}
+		if !matched && pinnedVersion(p.Version) {
+			for _, i := range idx {
+				if pinnedVersion(out[i].Version) {
+					addConflict(&out[i], p.Version+" in "+SBOMSource)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// packageIdentity is the package URL of a package without its version, or
+// "ecosystem:name" for packages that have none.
+func packageIdentity(p PackageRef) string {
+	q := p
+	q.Version = ""
+	if purl := q.PURL(); purl != "" {
+		return purl
+	}
+	return p.Ecosystem + ":" + strings.ToLower(p.Name)
+}
+
+func addSeenIn(p *PackageRef, source string) {
+	if len(p.SeenIn) == 0 {
+		p.SeenIn = []string{p.Source}
+	}
+	if !containsString(p.SeenIn, source) {
+		p.SeenIn = append(p.SeenIn, source)
+	}
+}
+
+func addConflict(p *PackageRef, conflict string) {
+	if !containsString(p.VersionConflicts, conflict) {
+		p.VersionConflicts = append(p.VersionConflicts, conflict)
+	}
+}
@@ -5,32 +5,231 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 )
 
+// npmLockPackage is an entry of the "packages" map in a v2/v3 lockfile.
+// The key "" is the root project, keys without node_modules are workspaces.
+type npmLockPackage struct {
+	Name                 string            `json:"name"`
+	Version              string            `json:"version"`
+	Resolved             string            `json:"resolved"`
+	Integrity            string            `json:"integrity"`
+	Link                 bool              `json:"link"`
+	Dev                  bool              `json:"dev"`
+	Optional             bool              `json:"optional"`
+	DevOptional          bool              `json:"devOptional"`
+	Peer                 bool              `json:"peer"`
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+}
+
+// npmLegacyDep is an entry of the nested "dependencies" tree used by v1 lockfiles.
+type npmLegacyDep struct {
+	Version      string                  `json:"version"`
+	Integrity    string                  `json:"integrity"`
+	Dev          bool                    `json:"dev"`
+	Optional     bool                    `json:"optional"`
+	Requires     map[string]string       `json:"requires"`
+	Dependencies map[string]npmLegacyDep `json:"dependencies"`
+}
+
+type npmLockfile struct {
+	LockfileVersion int                       `json:"lockfileVersion"`
+	Packages        map[string]npmLockPackage `json:"packages"`
+	Dependencies    map[string]npmLegacyDep   `json:"dependencies"`
+}
+
 func DiscoverNpm(dir string) []PackageRef {
 	var refs []PackageRef
-	// quick parse for lockfiles (best-effort summaries)
 	lockFiles := []string{"package-lock.json", "npm-shrinkwrap.json"}
 	for _, lf := range lockFiles {
-		p := filepath.Join(dir, lf)
-		b, err := os.ReadFile(p)
+		b, err := os.ReadFile(filepath.Join(dir, lf))
 		if err != nil {
 			continue
 		}
-		// package-lock v2+ has "packages" object with many entries; we'll just extract top-level deps.
-		var obj map[string]any
-		if json.Unmarshal(b, &obj) != nil {
+		var lock npmLockfile
+		if json.Unmarshal(b, &lock) != nil {
 			continue
 		}
-		if deps, ok := obj["dependencies"].(map[string]any); ok {
-			for name, v := range deps {
-				mv, _ := v.(map[string]any)
-				ver, _ := mv["version"].(string)
-				refs = append(refs, PackageRef{Ecosystem: "npm", Name: name, Version: ver, Source: lf})
-			}
+		pkgs := lock.Packages
+		if len(pkgs) == 0 {
+			// v1 lockfile: rebuild the same path-keyed layout from the nested tree
+			pkgs = map[string]npmLockPackage{"": readPackageJSON(dir)}
+			flattenLegacyNpm(pkgs, "", lock.Dependencies)
 		}
+		refs = append(refs, npmRefsFromPackages(pkgs, lf)...)
 	}
-	// yarn.lock / pnpm-lock.yaml could be added here.
+	refs = append(refs, discoverYarn(dir)...)
+	refs = append(refs, discoverPnpm(dir)...)
 	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
 	return refs
 }
+
+// npmRefsFromPackages turns a path-keyed lockfile package map into PackageRefs,
+// resolving every declared dependency the way node does (nearest node_modules
+// going up the tree) to record parent relationships.
+func npmRefsFromPackages(pkgs map[string]npmLockPackage, source string) []PackageRef {
+	keys := make([]string, 0, len(pkgs))
+	for k := range pkgs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	byPath := make(map[string]*PackageRef)
+	for _, k := range keys {
+		p := pkgs[k]
+		if !strings.Contains(k, "node_modules/") || p.Link {
+			continue
+		}
+		byPath[k] = &PackageRef{
+			Ecosystem: "npm",
+			Name:      npmPackageName(k, p.Name),
+			Version:   p.Version,
+			Source:    source,
+			Integrity: p.Integrity,
+			Path:      k,
+			Dev:       p.Dev || p.DevOptional,
+			Optional:  p.Optional || p.DevOptional,
+			Peer:      p.Peer,
+		}
+	}
+
+	for _, k := range keys {
+		p := pkgs[k]
+		if p.Link {
+			continue
+		}
+		isProject := !strings.Contains(k, "node_modules/")
+		required := []map[string]string{p.Dependencies, p.OptionalDependencies, p.PeerDependencies}
+		if isProject {
+			// only the root and workspaces carry their devDependencies in the lockfile
+			required = append(required, p.DevDependencies)
+		}
+		for _, m := range required {
+			for name := range m {
+				child := byPath[resolveNpmPath(pkgs, k, name)]
+				if child == nil {
+					continue
+				}
+				if isProject {
+					child.Direct = true
+					continue
+				}
+				parent := byPath[k].Name
+				if !containsString(child.Parents, parent) {
+					child.Parents = append(child.Parents, parent)
+				}
+			}
+		}
+	}
+
+	// v1 lockfiles without a package.json have no root entry to resolve from
+	if len(pkgs[""].Dependencies)+len(pkgs[""].DevDependencies) == 0 {
+		for _, r := range byPath {
+			if len(r.Parents) == 0 {
+				r.Direct = true
+			}
+		}
+	}
+
+	// the same name@version can be installed at several paths; keep one ref
+	var refs []PackageRef
+	seen := make(map[string]int)
+	for _, k := range keys {
+		r := byPath[k]
+		if r == nil {
+			continue
+		}
+		id := r.Name + "@" + r.Version
+		if i, ok := seen[id]; ok {
+			merged := &refs[i]
+			merged.Direct = merged.Direct || r.Direct
+			merged.Dev = merged.Dev && r.Dev
+			merged.Optional = merged.Optional && r.Optional
+			merged.Peer = merged.Peer && r.Peer
+			for _, parent := range r.Parents {
+				if !containsString(merged.Parents, parent) {
+					merged.Parents = append(merged.Parents, parent)
+				}
+			}
+			continue
+		}
+		seen[id] = len(refs)
+		refs = append(refs, *r)
+	}
+	return refs
+}
+
+// resolveNpmPath finds the lockfile key that a dependency named name, required
+// from the package at key from, resolves to. Returns "" if it is not installed.
+func resolveNpmPath(pkgs map[string]npmLockPackage, from, name string) string {
+	base := from
+	for {
+		cand := "node_modules/" + name
+		if base != "" {
+			cand = base + "/node_modules/" + name
+		}
+		if _, ok := pkgs[cand]; ok {
+			return cand
+		}
+		if base == "" {
+			return ""
+		}
+		idx := strings.LastIndex(base, "node_modules/")
+		if idx < 0 {
+			// workspace directory, fall back to the root node_modules
+			base = ""
+			continue
+		}
+		base = strings.TrimSuffix(base[:idx], "/")
+	}
+}
+
+// npmPackageName derives the package name from a lockfile key such as
+// "node_modules/a/node_modules/@scope/b", unless the entry names itself.
+func npmPackageName(key, declared string) string {
+	if declared != "" {
+		return declared
+	}
+	idx := strings.LastIndex(key, "node_modules/")
+	return key[idx+len("node_modules/"):]
+}
+
+func flattenLegacyNpm(pkgs map[string]npmLockPackage, base string, deps map[string]npmLegacyDep) {
+	for name, d := range deps {
+		key := "node_modules/" + name
+		if base != "" {
+			key = base + "/node_modules/" + name
+		}
+		pkgs[key] = npmLockPackage{
+			Version:      d.Version,
+			Integrity:    d.Integrity,
+			Dev:          d.Dev,
+			Optional:     d.Optional,
+			Dependencies: d.Requires,
+		}
+		flattenLegacyNpm(pkgs, key, d.Dependencies)
+	}
+}
+
+// readPackageJSON loads the dependency maps of dir/package.json as a root
+// lockfile entry. A missing or invalid file yields an empty entry.
+func readPackageJSON(dir string) npmLockPackage {
+	var p npmLockPackage
+	if b, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		_ = json.Unmarshal(b, &p)
+	}
+	return p
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package deps
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DiscoverNodeModules lists the packages installed in dir/node_modules,
+// following nested node_modules directories the way node resolves them.
+// Path is the package directory relative to dir, matching the keys of
+// package-lock.json. Symlinked workspace packages are skipped; pnpm's store
+// is only seen through the top-level links.
+func DiscoverNodeModules(dir string) []PackageRef {
+	var refs []PackageRef
+	visited := make(map[string]bool)
+	var scan func(rel string)
+	scan = func(rel string) {
+		nm := filepath.Join(dir, filepath.FromSlash(rel))
+		real, err := filepath.EvalSymlinks(nm)
+		if err != nil || visited[real] {
+			return
+		}
+		visited[real] = true
+
+		var pkgDirs []string
+		entries, _ := os.ReadDir(nm)
+		for _, e := range entries {
+			name := e.Name()
+			if strings.HasPrefix(name, ".") {
+				continue // .bin, .package-lock.json, .pnpm
+			}
+			if strings.HasPrefix(name, "@") {
+				scoped, _ := os.ReadDir(filepath.Join(nm, name))
+				for _, s := range scoped {
+					pkgDirs = append(pkgDirs, rel+"/"+name+"/"+s.Name())
+				}
+				continue
+			}
+			pkgDirs = append(pkgDirs, rel+"/"+name)
+		}
+
+		for _, p := range pkgDirs {
+			pkgDir := filepath.Join(dir, filepath.FromSlash(p))
+			if target, err := filepath.EvalSymlinks(pkgDir); err != nil || !strings.Contains("/"+filepath.ToSlash(target), "/node_modules/") {
+				continue // workspace link
+			}
+			b, err := os.ReadFile(filepath.Join(pkgDir, "package.json"))
+			if err != nil {
+				continue
+			}
+			var pj struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			}
+			if json.Unmarshal(b, &pj) != nil {
+				continue
+			}
+			refs = append(refs, PackageRef{
+				Ecosystem: "npm",
+				Name:      npmPackageName(p, pj.Name),
+				Version:   pj.Version,
+				Source:    p + "/package.json",
+				Path:      p,
+				Location:  p,
+			})
+			scan(p + "/node_modules")
+		}
+	}
+	scan("node_modules")
+
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Path < refs[j].Path })
+	return refs
+}
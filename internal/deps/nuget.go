@@ -0,0 +1,422 @@
+package deps
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type nugetLockEntry struct {
+	Type         string            `json:"type"` // Direct, Transitive, Project, CentralTransitive
+	Requested    string            `json:"requested"`
+	Resolved     string            `json:"resolved"`
+	ContentHash  string            `json:"contentHash"`
+	Dependencies map[string]string `json:"dependencies"`
+}
+
+type nugetLockfile struct {
+	Version      int                                  `json:"version"`
+	Dependencies map[string]map[string]nugetLockEntry `json:"dependencies"` // target framework -> package id -> entry
+}
+
+type msbuildItem struct {
+	Include            string `xml:"Include,attr"`
+	Update             string `xml:"Update,attr"`
+	Version            string `xml:"Version,attr"`
+	VersionElem        string `xml:"Version"`
+	VersionOverride    string `xml:"VersionOverride,attr"`
+	PrivateAssets      string `xml:"PrivateAssets,attr"`
+	PrivateAssetsElem  string `xml:"PrivateAssets"`
+	DevelopmentDepAttr string `xml:"DevelopmentDependency,attr"`
+	DevelopmentDepElem string `xml:"DevelopmentDependency"`
+}
+
+func (it msbuildItem) version() string {
+	for _, v := range []string{it.VersionOverride, it.Version, it.VersionElem} {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// dev reports whether the package is build-time only (analyzers, source
+// generators, SourceLink), which is what PrivateAssets="all" expresses.
+func (it msbuildItem) dev() bool {
+	pa := strings.ToLower(it.PrivateAssets + it.PrivateAssetsElem)
+	return strings.Contains(pa, "all") || strings.EqualFold(it.DevelopmentDepAttr+it.DevelopmentDepElem, "true")
+}
+
+type msbuildProject struct {
+	PropertyGroups []xmlProperties `xml:"PropertyGroup"`
+	ItemGroups     []struct {
+		Condition              string        `xml:"Condition,attr"`
+		PackageReference       []msbuildItem `xml:"PackageReference"`
+		PackageVersion         []msbuildItem `xml:"PackageVersion"`
+		GlobalPackageReference []msbuildItem `xml:"GlobalPackageReference"`
+	} `xml:"ItemGroup"`
+}
+
+var (
+	msbuildPropRe      = regexp.MustCompile(`\$\(([A-Za-z_][A-Za-z0-9_.-]*)\)`)
+	msbuildFrameworkRe = regexp.MustCompile(`'\$\(TargetFramework\)'\s*==\s*'([^']+)'`)
+)
+
+// DiscoverNuget reports the .NET projects (*.csproj, *.fsproj, *.vbproj) in
+// dir and, when dir has a solution (*.sln, *.slnx), the projects below dir
+// it lists in directories that recursive discovery visits. Projects with a
+// packages.lock.json are reported from the lockfile, per target framework
+// and with transitive edges; others from their PackageReference items, with
+// versions from Directory.Packages.props when central package management is
+// used. Target frameworks are recorded in NativeScope and the project
+// directory in Subproject.
+func DiscoverNuget(dir string, opts Options) []PackageRef {
+	root := opts.root
+	if root == "" {
+		root = dir
+	}
+	var refs []PackageRef
+	for _, proj := range nugetProjects(dir) {
+		pdir := filepath.Dir(proj)
+		sub := relSource(dir, pdir)
+		if sub == "." {
+			sub = ""
+		} else if opts.visited != nil && !opts.visited[relSource(root, pdir)] {
+			// a listed project in an excluded or ignored directory
+			continue
+		}
+
+		declared := readNugetDeclarations(root, proj)
+		locked, ok := readNugetLock(filepath.Join(pdir, "packages.lock.json"), declared)
+		if !ok {
+			locked = declaredNugetRefs(declared)
+		}
+		for _, r := range locked {
+			r.Subproject = sub
+			refs = append(refs, r)
+		}
+	}
+	return refs
+}
+
+// nugetProjects returns the project files in dir and those listed by the
+// solutions in dir.
+func nugetProjects(dir string) []string {
+	var projects []string
+	for _, pattern := range []string{"*.csproj", "*.fsproj", "*.vbproj"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		projects = append(projects, matches...)
+	}
+	for _, p := range nugetSolutionProjects(dir) {
+		if !containsString(projects, p) {
+			projects = append(projects, p)
+		}
+	}
+	sort.Strings(projects)
+	return projects
+}
+
+// nugetSolutionDirs returns the directories below dir of the projects its
+// solutions list, which DiscoverNuget reports itself.
+func nugetSolutionDirs(dir string) []string {
+	var dirs []string
+	for _, p := range nugetSolutionProjects(dir) {
+		if d := filepath.Dir(p); d != filepath.Clean(dir) && !containsString(dirs, d) {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+var slnProjectRe = regexp.MustCompile(`(?m)^Project\("\{[^}]*\}"\)\s*=\s*"[^"]*",\s*"([^"]+)"`)
+
+// nugetSolutionProjects returns the existing project files below dir that
+// the *.sln and *.slnx files in dir list; solution folders and projects
+// outside dir are left out.
+func nugetSolutionProjects(dir string) []string {
+	var listed []string
+	slns, _ := filepath.Glob(filepath.Join(dir, "*.sln"))
+	for _, sln := range slns {
+		if b, err := os.ReadFile(sln); err == nil {
+			for _, m := range slnProjectRe.FindAllStringSubmatch(string(b), -1) {
+				listed = append(listed, m[1])
+			}
+		}
+	}
+	slnxs, _ := filepath.Glob(filepath.Join(dir, "*.slnx"))
+	for _, slnx := range slnxs {
+		f, err := os.Open(slnx)
+		if err != nil {
+			continue
+		}
+		dec := xml.NewDecoder(f)
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				break
+			}
+			if el, ok := tok.(xml.StartElement); ok && el.Name.Local == "Project" {
+				for _, a := range el.Attr {
+					if a.Name.Local == "Path" {
+						listed = append(listed, a.Value)
+					}
+				}
+			}
+		}
+		f.Close()
+	}
+
+	var projects []string
+	for _, l := range listed {
+		p := filepath.Join(dir, filepath.FromSlash(strings.ReplaceAll(l, `\`, "/")))
+		switch filepath.Ext(p) {
+		case ".csproj", ".fsproj", ".vbproj":
+		default:
+			continue
+		}
+		if rel, err := filepath.Rel(dir, p); err != nil || strings.HasPrefix(rel, "..") || !fileExists(p) {
+			continue
+		}
+		if !containsString(projects, p) {
+			projects = append(projects, p)
+		}
+	}
+	return projects
+}
+
+// nugetDeclared is a PackageReference with its effective version.
+type nugetDeclared struct {
+	name       string
+	version    string
+	dev        bool
+	frameworks []string // from the ItemGroup condition, else all of the project's
+	source     string
+}
+
+// readNugetDeclarations returns the PackageReference items of a project plus
+// the GlobalPackageReference items of the Directory.Packages.props that
+// applies to it, resolving central versions and $(Property) references.
+func readNugetDeclarations(root, proj string) []nugetDeclared {
+	p, ok := readMSBuild(proj)
+	if !ok {
+		return nil
+	}
+	props := make(map[string]string)
+	central := make(map[string]string)
+	var global []msbuildItem
+
+	// Directory.Build.props and Directory.Packages.props apply from the
+	// nearest ancestor directory (within the scanned tree)
+	for _, name := range []string{"Directory.Build.props", "Directory.Packages.props"} {
+		path := findUpwards(root, filepath.Dir(proj), name)
+		if path == "" {
+			continue
+		}
+		dp, ok := readMSBuild(path)
+		if !ok {
+			continue
+		}
+		for _, pg := range dp.PropertyGroups {
+			for k, v := range pg {
+				props[k] = v
+			}
+		}
+		for _, ig := range dp.ItemGroups {
+			for _, it := range ig.PackageVersion {
+				central[strings.ToLower(it.Include)] = it.version()
+			}
+			for _, it := range ig.GlobalPackageReference {
+				it.PrivateAssets = "all" // global references are build-time by definition
+				global = append(global, it)
+			}
+		}
+	}
+	for _, pg := range p.PropertyGroups {
+		for k, v := range pg {
+			props[k] = v
+		}
+	}
+	expand := func(s string) string {
+		return msbuildPropRe.ReplaceAllStringFunc(s, func(ref string) string {
+			if v, ok := props[ref[2:len(ref)-1]]; ok {
+				return v
+			}
+			return ref
+		})
+	}
+
+	var projectFrameworks []string
+	for _, tfm := range strings.Split(expand(props["TargetFrameworks"]+";"+props["TargetFramework"]), ";") {
+		if tfm = strings.TrimSpace(tfm); tfm != "" && !containsString(projectFrameworks, tfm) {
+			projectFrameworks = append(projectFrameworks, tfm)
+		}
+	}
+
+	source := filepath.Base(proj)
+	var out []nugetDeclared
+	add := func(it msbuildItem, frameworks []string) {
+		if it.Include == "" {
+			return // Update="..." items only modify existing references
+		}
+		if len(frameworks) == 0 {
+			frameworks = projectFrameworks
+		}
+		v := it.version()
+		if v == "" {
+			v = central[strings.ToLower(it.Include)]
+		}
+		out = append(out, nugetDeclared{
+			name:       it.Include,
+			version:    expand(v),
+			dev:        it.dev(),
+			frameworks: frameworks,
+			source:     source,
+		})
+	}
+	for _, ig := range p.ItemGroups {
+		var frameworks []string
+		for _, m := range msbuildFrameworkRe.FindAllStringSubmatch(ig.Condition, -1) {
+			frameworks = append(frameworks, m[1])
+		}
+		for _, it := range ig.PackageReference {
+			add(it, frameworks)
+		}
+	}
+	for _, it := range global {
+		add(it, nil)
+	}
+	return out
+}
+
+func readMSBuild(path string) (msbuildProject, bool) {
+	var p msbuildProject
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return p, false
+	}
+	return p, xml.Unmarshal(b, &p) == nil
+}
+
+// findUpwards looks for name in dir and its parents, stopping at root.
+func findUpwards(root, dir, name string) string {
+	root = filepath.Clean(root)
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if p := filepath.Join(d, name); fileExists(p) {
+			return p
+		}
+		if d == root || d == filepath.Dir(d) || !strings.HasPrefix(d, root) {
+			return ""
+		}
+	}
+}
+
+func fileExists(path string) bool {
+	st, err := os.Stat(path)
+	return err == nil && !st.IsDir()
+}
+
+// readNugetLock reads packages.lock.json. Each target framework is resolved
+// as its own graph; a package present in several frameworks is reported once
+// per version with the frameworks listed in NativeScope.
+func readNugetLock(path string, declared []nugetDeclared) ([]PackageRef, bool) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var lock nugetLockfile
+	if json.Unmarshal(b, &lock) != nil {
+		return nil, false
+	}
+	source := filepath.Base(path)
+
+	dev := make(map[string]bool)
+	for _, d := range declared {
+		dev[strings.ToLower(d.name)] = d.dev
+	}
+
+	var refs []PackageRef
+	index := make(map[string]int)
+	for _, tfm := range sortedKeys(lock.Dependencies) {
+		entries := lock.Dependencies[tfm]
+		g := newLockGraph()
+		projects := make(map[string]bool)
+		var roots, devRoots []string
+		for name, e := range entries {
+			key := strings.ToLower(name)
+			n := &lockNode{ref: PackageRef{
+				Ecosystem: "nuget",
+				Name:      name,
+				Version:   e.Resolved,
+				Source:    source,
+				Integrity: e.ContentHash,
+			}}
+			for dep := range e.Dependencies {
+				n.deps = append(n.deps, lockEdge{key: strings.ToLower(dep)})
+			}
+			g.add([]string{key}, n)
+			switch e.Type {
+			case "Project":
+				projects[key] = true
+				roots = append(roots, key) // walked through, not reported
+			case "Direct":
+				if dev[key] {
+					devRoots = append(devRoots, key)
+				} else {
+					roots = append(roots, key)
+				}
+			}
+		}
+
+		for _, r := range g.refs(roots, devRoots) {
+			if projects[strings.ToLower(r.Name)] {
+				continue
+			}
+			// project references are code in this repository, not packages
+			var parents []string
+			for _, p := range r.Parents {
+				if !projects[strings.ToLower(p)] {
+					parents = append(parents, p)
+				}
+			}
+			r.Parents = parents
+			id := strings.ToLower(r.Name) + "@" + r.Version
+			if i, ok := index[id]; ok {
+				merged := &refs[i]
+				merged.NativeScope += "," + tfm
+				merged.Direct = merged.Direct || r.Direct
+				merged.Dev = merged.Dev && r.Dev
+				for _, p := range r.Parents {
+					if !containsString(merged.Parents, p) {
+						merged.Parents = append(merged.Parents, p)
+					}
+				}
+				continue
+			}
+			r.NativeScope = tfm
+			index[id] = len(refs)
+			refs = append(refs, r)
+		}
+	}
+	sort.SliceStable(refs, func(i, j int) bool { return strings.ToLower(refs[i].Name) < strings.ToLower(refs[j].Name) })
+	return refs, true
+}
+
+func declaredNugetRefs(declared []nugetDeclared) []PackageRef {
+	var refs []PackageRef
+	for _, d := range declared {
+		refs = append(refs, PackageRef{
+			Ecosystem:   "nuget",
+			Name:        d.name,
+			Version:     d.version,
+			Source:      d.source,
+			Direct:      true,
+			Dev:         d.dev,
+			NativeScope: strings.Join(d.frameworks, ","),
+		})
+	}
+	return refs
+}
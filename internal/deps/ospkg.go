@@ -0,0 +1,329 @@
+package deps
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DiscoverDpkg lists the packages dpkg has installed in the root filesystem
+// dir, from var/lib/dpkg/status or, on distroless images, the files in
+// var/lib/dpkg/status.d. Licenses come from the machine-readable
+// usr/share/doc/<package>/copyright files where present.
+func DiscoverDpkg(dir string) []PackageRef {
+	rel := ReadOSRelease(dir)
+	files := []string{filepath.Join(dir, "var", "lib", "dpkg", "status")}
+	extra, _ := filepath.Glob(filepath.Join(dir, "var", "lib", "dpkg", "status.d", "*"))
+	files = append(files, extra...)
+
+	var refs []PackageRef
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		for _, p := range readControlParagraphs(f) {
+			if p["Package"] == "" || !dpkgInstalled(p["Status"]) {
+				continue
+			}
+			ref := PackageRef{
+				Ecosystem: "deb",
+				Name:      p["Package"],
+				Version:   p["Version"],
+				Source:    relSource(dir, path),
+				OS:        &OSInfo{Distro: rel.ID, DistroVersion: rel.VersionID, Arch: p["Architecture"]},
+				Licenses:  debianLicenses(dir, p["Package"]),
+			}
+			// the file list dpkg keeps per package, written by the install itself
+			for _, list := range []string{p["Package"] + ":" + p["Architecture"] + ".list", p["Package"] + ".list"} {
+				if fileExists(filepath.Join(dir, "var", "lib", "dpkg", "info", list)) {
+					ref.Location = "var/lib/dpkg/info/" + list
+					break
+				}
+			}
+			if src := p["Source"]; src != "" {
+				// "Source: glibc (2.36-9)"; without a version it is the package's
+				name, version, _ := strings.Cut(src, " ")
+				ref.OS.SourceName = name
+				ref.OS.SourceVersion = strings.Trim(strings.TrimSpace(version), "()")
+				if ref.OS.SourceVersion == "" {
+					ref.OS.SourceVersion = ref.Version
+				}
+			}
+			refs = append(refs, ref)
+		}
+		f.Close()
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	return refs
+}
+
+// dpkgInstalled reports whether a Status field ("install ok installed")
+// describes an installed package; status.d entries have none.
+func dpkgInstalled(status string) bool {
+	fields := strings.Fields(status)
+	return len(fields) == 0 || fields[len(fields)-1] == "installed"
+}
+
+// debianLicenses collects the License fields of a DEP-5 copyright file.
+func debianLicenses(dir, pkg string) []string {
+	f, err := os.Open(filepath.Join(dir, "usr", "share", "doc", pkg, "copyright"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var licenses []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if v, ok := strings.CutPrefix(sc.Text(), "License:"); ok {
+			if v = strings.TrimSpace(v); v != "" && !containsString(licenses, v) {
+				licenses = append(licenses, v)
+			}
+		}
+	}
+	return licenses
+}
+
+// readControlParagraphs parses Debian control-style data: "Field: value"
+// paragraphs separated by blank lines. Continuation lines are dropped.
+func readControlParagraphs(r io.Reader) []map[string]string {
+	var out []map[string]string
+	cur := map[string]string{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" {
+			if len(cur) > 0 {
+				out = append(out, cur)
+				cur = map[string]string{}
+			}
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			cur[k] = strings.TrimSpace(v)
+		}
+	}
+	if len(cur) > 0 {
+		out = append(out, cur)
+	}
+	return out
+}
+
+// DiscoverApk lists the packages in the Alpine database
+// lib/apk/db/installed of the root filesystem dir.
+func DiscoverApk(dir string) []PackageRef {
+	path := filepath.Join(dir, "lib", "apk", "db", "installed")
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	rel := ReadOSRelease(dir)
+
+	var refs []PackageRef
+	var cur *PackageRef
+	flush := func() {
+		if cur != nil && cur.Name != "" {
+			refs = append(refs, *cur)
+		}
+		cur = nil
+	}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		if len(line) < 2 || line[1] != ':' {
+			continue
+		}
+		if cur == nil {
+			cur = &PackageRef{
+				Ecosystem: "apk",
+				Source:    relSource(dir, path),
+				OS:        &OSInfo{Distro: rel.ID, DistroVersion: rel.VersionID},
+			}
+		}
+		v := line[2:]
+		switch line[0] {
+		case 'P':
+			cur.Name = v
+		case 'V':
+			cur.Version = v
+		case 'A':
+			cur.OS.Arch = v
+		case 'L':
+			if v != "" {
+				cur.Licenses = []string{v}
+			}
+		case 'o':
+			cur.OS.SourceName = v
+		}
+	}
+	flush()
+	for i := range refs {
+		if refs[i].OS.SourceName != "" {
+			refs[i].OS.SourceVersion = refs[i].Version // aports build every subpackage from one origin version
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	return refs
+}
+
+// rpmdbPaths are where rpm keeps its sqlite database (Fedora 33+, RHEL 9,
+// openSUSE).
+var rpmdbPaths = []string{"var/lib/rpm/rpmdb.sqlite", "usr/lib/sysimage/rpm/rpmdb.sqlite"}
+
+// DiscoverRpm lists the packages in the rpm sqlite database of the root
+// filesystem dir. Older Berkeley DB and ndb databases are not read.
+func DiscoverRpm(dir string) []PackageRef {
+	rel := ReadOSRelease(dir)
+	var refs []PackageRef
+	for _, p := range rpmdbPaths {
+		path := filepath.Join(dir, filepath.FromSlash(p))
+		if !fileExists(path) {
+			continue
+		}
+		db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro&immutable=1")
+		if err != nil {
+			continue
+		}
+		rows, err := db.Query("SELECT blob FROM Packages")
+		if err != nil {
+			db.Close()
+			continue
+		}
+		for rows.Next() {
+			var blob []byte
+			if rows.Scan(&blob) != nil {
+				continue
+			}
+			h := parseRpmHeader(blob)
+			name := h.str(rpmTagName)
+			if name == "" || name == "gpg-pubkey" {
+				continue // imported signing keys are stored as pseudo-packages
+			}
+			ref := PackageRef{
+				Ecosystem: "rpm",
+				Name:      name,
+				Version:   h.str(rpmTagVersion) + "-" + h.str(rpmTagRelease),
+				Source:    p,
+				OS:        &OSInfo{Distro: rel.ID, DistroVersion: rel.VersionID, Arch: h.str(rpmTagArch)},
+			}
+			if epoch, ok := h.int(rpmTagEpoch); ok && epoch > 0 {
+				ref.OS.Epoch = strconv.Itoa(epoch)
+			}
+			if l := h.str(rpmTagLicense); l != "" {
+				ref.Licenses = []string{l}
+			}
+			ref.OS.SourceName, ref.OS.SourceVersion = splitSourceRpm(h.str(rpmTagSourceRPM))
+			refs = append(refs, ref)
+		}
+		rows.Close()
+		db.Close()
+		break
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	return refs
+}
+
+const (
+	rpmTagName      = 1000
+	rpmTagVersion   = 1001
+	rpmTagRelease   = 1002
+	rpmTagEpoch     = 1003
+	rpmTagLicense   = 1014
+	rpmTagArch      = 1022
+	rpmTagSourceRPM = 1044
+
+	rpmTypeInt32       = 4
+	rpmTypeString      = 6
+	rpmTypeStringArray = 8
+	rpmTypeI18NString  = 9
+)
+
+type rpmEntry struct {
+	typ, offset, count uint32
+}
+
+// rpmHeader is a header blob as rpm stores it in its database: entry count
+// and data size, the index entries, then the data store.
+type rpmHeader struct {
+	entries map[uint32]rpmEntry
+	data    []byte
+}
+
+func parseRpmHeader(b []byte) rpmHeader {
+	h := rpmHeader{entries: map[uint32]rpmEntry{}}
+	if len(b) < 8 {
+		return h
+	}
+	il := binary.BigEndian.Uint32(b[0:4])
+	dl := binary.BigEndian.Uint32(b[4:8])
+	start := 8 + uint64(il)*16
+	if start+uint64(dl) > uint64(len(b)) {
+		return h
+	}
+	for i := uint64(0); i < uint64(il); i++ {
+		e := b[8+i*16:]
+		h.entries[binary.BigEndian.Uint32(e[0:4])] = rpmEntry{
+			typ:    binary.BigEndian.Uint32(e[4:8]),
+			offset: binary.BigEndian.Uint32(e[8:12]),
+			count:  binary.BigEndian.Uint32(e[12:16]),
+		}
+	}
+	h.data = b[start : start+uint64(dl)]
+	return h
+}
+
+func (h rpmHeader) str(tag uint32) string {
+	e, ok := h.entries[tag]
+	if !ok || e.offset >= uint32(len(h.data)) {
+		return ""
+	}
+	switch e.typ {
+	case rpmTypeString, rpmTypeStringArray, rpmTypeI18NString:
+		s := h.data[e.offset:]
+		if end := strings.IndexByte(string(s), 0); end >= 0 {
+			s = s[:end]
+		}
+		return string(s)
+	}
+	return ""
+}
+
+func (h rpmHeader) int(tag uint32) (int, bool) {
+	e, ok := h.entries[tag]
+	if !ok || e.typ != rpmTypeInt32 || uint64(e.offset)+4 > uint64(len(h.data)) {
+		return 0, false
+	}
+	return int(binary.BigEndian.Uint32(h.data[e.offset:])), true
+}
+
+// splitSourceRpm splits "bash-5.1.8-6.el9.src.rpm" into "bash" and
+// "5.1.8-6.el9".
+func splitSourceRpm(s string) (name, version string) {
+	s = strings.TrimSuffix(strings.TrimSuffix(s, ".rpm"), ".src")
+	i := strings.LastIndex(s, "-")
+	if i < 0 {
+		return s, ""
+	}
+	j := strings.LastIndex(s[:i], "-")
+	if j < 0 {
+		return s, ""
+	}
+	return s[:j], s[j+1:]
+}
@@ -0,0 +1,46 @@
+package deps
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OSRelease identifies the distribution of a root filesystem.
+type OSRelease struct {
+	ID         string // "debian", "alpine", "rhel"
+	VersionID  string // "12", "3.20.1"
+	PrettyName string
+}
+
+// ReadOSRelease reads etc/os-release (or usr/lib/os-release) below root.
+// The zero value is returned when neither exists.
+func ReadOSRelease(root string) OSRelease {
+	var rel OSRelease
+	for _, name := range []string{"etc/os-release", "usr/lib/os-release"} {
+		f, err := os.Open(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			k, v, ok := strings.Cut(strings.TrimSpace(sc.Text()), "=")
+			if !ok {
+				continue
+			}
+			v = strings.Trim(v, `"'`)
+			switch k {
+			case "ID":
+				rel.ID = v
+			case "VERSION_ID":
+				rel.VersionID = v
+			case "PRETTY_NAME":
+				rel.PrettyName = v
+			}
+		}
+		f.Close()
+		return rel
+	}
+	return rel
+}
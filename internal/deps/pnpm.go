@@ -0,0 +1,157 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+type pnpmImporterDep struct {
+	Specifier string `yaml:"specifier"`
+	Version   string `yaml:"version"`
+}
+
+type pnpmImporter struct {
+	Dependencies         map[string]pnpmImporterDep `yaml:"dependencies"`
+	DevDependencies      map[string]pnpmImporterDep `yaml:"devDependencies"`
+	OptionalDependencies map[string]pnpmImporterDep `yaml:"optionalDependencies"`
+}
+
+// pnpmSnapshot carries the resolved dependencies of a package. In v9 these
+// live under "snapshots"; in v6 they are inlined in the "packages" entries.
+type pnpmSnapshot struct {
+	Resolution struct {
+		Integrity string `yaml:"integrity"`
+	} `yaml:"resolution"`
+	Optional             bool              `yaml:"optional"`
+	Dependencies         map[string]string `yaml:"dependencies"`
+	OptionalDependencies map[string]string `yaml:"optionalDependencies"`
+}
+
+type pnpmLockfile struct {
+	LockfileVersion string                  `yaml:"lockfileVersion"`
+	Importers       map[string]pnpmImporter `yaml:"importers"`
+	Packages        map[string]pnpmSnapshot `yaml:"packages"`
+	Snapshots       map[string]pnpmSnapshot `yaml:"snapshots"`
+
+	// lockfiles without workspaces keep the single importer at the top level
+	Dependencies         map[string]pnpmImporterDep `yaml:"dependencies"`
+	DevDependencies      map[string]pnpmImporterDep `yaml:"devDependencies"`
+	OptionalDependencies map[string]pnpmImporterDep `yaml:"optionalDependencies"`
+}
+
+// discoverPnpm reads pnpm-lock.yaml (v6 and v9 layouts). Each importer
+// (workspace package) is reported separately with its own closure of
+// dependencies, attributed through PackageRef.Subproject.
+func discoverPnpm(dir string) []PackageRef {
+	b, err := os.ReadFile(filepath.Join(dir, "pnpm-lock.yaml"))
+	if err != nil {
+		return nil
+	}
+	var lock pnpmLockfile
+	if yaml.Unmarshal(b, &lock) != nil {
+		return nil
+	}
+	if len(lock.Importers) == 0 {
+		lock.Importers = map[string]pnpmImporter{".": {
+			Dependencies:         lock.Dependencies,
+			DevDependencies:      lock.DevDependencies,
+			OptionalDependencies: lock.OptionalDependencies,
+		}}
+	}
+
+	g := newLockGraph()
+	snapshots := lock.Snapshots
+	if len(snapshots) == 0 {
+		snapshots = lock.Packages
+	}
+	for key, snap := range snapshots {
+		key = strings.TrimPrefix(key, "/")
+		name, version := pnpmSplitKey(key)
+		integrity := snap.Resolution.Integrity
+		if pkg, ok := lock.Packages[pnpmStripPeers(key)]; ok && integrity == "" {
+			integrity = pkg.Resolution.Integrity
+		}
+		n := &lockNode{ref: PackageRef{
+			Ecosystem: "npm",
+			Name:      name,
+			Version:   version,
+			Source:    "pnpm-lock.yaml",
+			Integrity: integrity,
+			Optional:  snap.Optional,
+		}}
+		for dep, ver := range snap.Dependencies {
+			n.deps = append(n.deps, lockEdge{key: pnpmDepKey(dep, ver)})
+		}
+		for dep, ver := range snap.OptionalDependencies {
+			n.deps = append(n.deps, lockEdge{key: pnpmDepKey(dep, ver), optional: true})
+		}
+		g.add([]string{key}, n)
+	}
+
+	importers := make([]string, 0, len(lock.Importers))
+	for path := range lock.Importers {
+		importers = append(importers, path)
+	}
+	sort.Strings(importers)
+
+	var refs []PackageRef
+	for _, path := range importers {
+		imp := lock.Importers[path]
+		prodRoots := append(pnpmRoots(imp.Dependencies), pnpmRoots(imp.OptionalDependencies)...)
+		devRoots := pnpmRoots(imp.DevDependencies)
+		sub := g.subgraph(append(append([]string{}, prodRoots...), devRoots...))
+		for _, r := range sub.refs(prodRoots, devRoots) {
+			if path != "." {
+				r.Subproject = path
+			}
+			refs = append(refs, r)
+		}
+	}
+	return refs
+}
+
+func pnpmRoots(deps map[string]pnpmImporterDep) []string {
+	var keys []string
+	for name, d := range deps {
+		// link: versions point at other workspace packages, not installed packages
+		if strings.HasPrefix(d.Version, "link:") {
+			continue
+		}
+		keys = append(keys, pnpmDepKey(name, d.Version))
+	}
+	return keys
+}
+
+// pnpmDepKey builds the package key a resolved dependency version refers to.
+// Aliased dependencies carry the real "name@version" as their version.
+func pnpmDepKey(name, version string) string {
+	// the "@" of a scoped alias target ("@scope/bar@1.0.0") is not the one
+	// before its version
+	if v := strings.TrimPrefix(pnpmStripPeers(version), "/"); len(v) > 1 && strings.Index(v[1:], "@") >= 0 {
+		return strings.TrimPrefix(version, "/")
+	}
+	return name + "@" + version
+}
+
+// pnpmSplitKey splits "@scope/name@1.0.0(peer@2.0.0)" into name and version.
+func pnpmSplitKey(key string) (string, string) {
+	key = pnpmStripPeers(key)
+	if key == "" {
+		return "", ""
+	}
+	if idx := strings.Index(key[1:], "@"); idx >= 0 {
+		return key[:idx+1], key[idx+2:]
+	}
+	return key, ""
+}
+
+func pnpmStripPeers(key string) string {
+	if idx := strings.Index(key, "("); idx > 0 {
+		return key[:idx]
+	}
+	return key
+}
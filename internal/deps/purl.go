@@ -0,0 +1,133 @@
+package deps
+
+import (
+	"fmt"
+	"strings"
+)
+
+// purlTypes maps ecosystem IDs to package URL types.
+var purlTypes = map[string]string{
+	"go":       "golang",
+	"npm":      "npm",
+	"python":   "pypi",
+	"maven":    "maven",
+	"cargo":    "cargo",
+	"nuget":    "nuget",
+	"composer": "composer",
+	"gem":      "gem",
+	"deb":      "deb",
+	"apk":      "apk",
+	"rpm":      "rpm",
+	"docker":   "docker",
+}
+
+// PURL returns the package URL of a package, or "" when its ecosystem has
+// no purl type. The version is left out unless it is exact. Distro
+// packages are namespaced by distribution and qualified with arch, distro
+// and epoch. GitHub Actions references become github purls, with the path
+// of an action or workflow inside its repository as subpath, or docker
+// purls for docker:// images; submodules hosted on GitHub become github
+// purls at their pinned commit.
+func (p PackageRef) PURL() string {
+	switch p.Ecosystem {
+	case "github-actions":
+		return actionPURL(p)
+	case "git-submodule":
+		if repo, ok := strings.CutPrefix(p.Name, "github.com/"); ok {
+			return formatPURL("github", repo, p.Version)
+		}
+		return ""
+	}
+	typ, ok := purlTypes[p.Ecosystem]
+	if !ok || p.Name == "" || p.Kind != "" || strings.Contains(p.Name, "${") {
+		return "" // ${ARG} is an image name left unresolved
+	}
+	name := p.Name
+	switch p.Ecosystem {
+	case "python":
+		name = normalizePyName(name)
+	case "maven":
+		name = strings.Replace(name, ":", "/", 1)
+	}
+	version := ""
+	if pinnedVersion(p.Version) {
+		version = p.Version
+	}
+	if p.OS == nil {
+		return formatPURL(typ, name, version)
+	}
+
+	if p.OS.Distro != "" {
+		name = p.OS.Distro + "/" + name
+	}
+	var qualifiers []string // sorted by key
+	if p.OS.Arch != "" {
+		qualifiers = append(qualifiers, "arch="+purlEscape(p.OS.Arch))
+	}
+	if p.OS.Distro != "" {
+		distro := p.OS.Distro
+		if p.OS.DistroVersion != "" {
+			distro += "-" + p.OS.DistroVersion
+		}
+		qualifiers = append(qualifiers, "distro="+purlEscape(distro))
+	}
+	if p.OS.Epoch != "" {
+		qualifiers = append(qualifiers, "epoch="+purlEscape(p.OS.Epoch))
+	}
+	purl := formatPURL(typ, name, version)
+	if len(qualifiers) > 0 {
+		purl += "?" + strings.Join(qualifiers, "&")
+	}
+	return purl
+}
+
+func actionPURL(p PackageRef) string {
+	if p.RefType == "docker" {
+		return formatPURL("docker", p.Name, p.Version)
+	}
+	repo := GitHubActionRepo(p)
+	if repo == "" {
+		return ""
+	}
+	purl := formatPURL("github", repo, p.Version)
+	if sub := strings.TrimPrefix(p.Name, repo); sub != "" {
+		purl += "#" + strings.TrimPrefix(sub, "/")
+	}
+	return purl
+}
+
+// PURL returns the package URL of a Go module.
+func (m GoModule) PURL() string {
+	version := m.Version
+	if version == "(devel)" {
+		version = ""
+	}
+	return formatPURL("golang", m.Path, version)
+}
+
+// formatPURL assembles "pkg:type/namespace/name@version", escaping each
+// path segment and the version.
+func formatPURL(typ, name, version string) string {
+	segs := strings.Split(name, "/")
+	for i, s := range segs {
+		segs[i] = purlEscape(s)
+	}
+	purl := "pkg:" + typ + "/" + strings.Join(segs, "/")
+	if version != "" {
+		purl += "@" + purlEscape(version)
+	}
+	return purl
+}
+
+func purlEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte(".-_~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,181 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+type pyprojectFile struct {
+	Project struct {
+		Name                 string              `toml:"name"`
+		Dependencies         []string            `toml:"dependencies"`
+		OptionalDependencies map[string][]string `toml:"optional-dependencies"`
+	} `toml:"project"`
+	// PEP 735 groups; entries are requirement strings or {include-group = "..."} tables
+	DependencyGroups map[string][]any `toml:"dependency-groups"`
+	Tool             struct {
+		Poetry struct {
+			Dependencies    map[string]any `toml:"dependencies"`
+			DevDependencies map[string]any `toml:"dev-dependencies"`
+			Group           map[string]struct {
+				Dependencies map[string]any `toml:"dependencies"`
+			} `toml:"group"`
+		} `toml:"poetry"`
+	} `toml:"tool"`
+}
+
+type pipfile struct {
+	Packages    map[string]any `toml:"packages"`
+	DevPackages map[string]any `toml:"dev-packages"`
+}
+
+// pyDeclared is a dependency as written in a manifest, before locking.
+type pyDeclared struct {
+	pyRequirement
+	Dev      bool
+	Optional bool
+	Group    string
+}
+
+// readPyproject returns the dependencies declared in pyproject.toml, covering
+// PEP 621 [project] tables, PEP 735 [dependency-groups] and [tool.poetry].
+func readPyproject(dir string) ([]pyDeclared, bool) {
+	b, err := os.ReadFile(filepath.Join(dir, "pyproject.toml"))
+	if err != nil {
+		return nil, false
+	}
+	var pp pyprojectFile
+	if toml.Unmarshal(b, &pp) != nil {
+		return nil, false
+	}
+
+	var out []pyDeclared
+	for _, req := range pp.Project.Dependencies {
+		if r, ok := parsePEP508(req); ok {
+			out = append(out, pyDeclared{pyRequirement: r})
+		}
+	}
+	for _, extra := range sortedKeys(pp.Project.OptionalDependencies) {
+		for _, req := range pp.Project.OptionalDependencies[extra] {
+			if r, ok := parsePEP508(req); ok {
+				out = append(out, pyDeclared{pyRequirement: r, Optional: true, Group: extra})
+			}
+		}
+	}
+	for _, group := range sortedKeys(pp.DependencyGroups) {
+		for _, entry := range pp.DependencyGroups[group] {
+			req, ok := entry.(string)
+			if !ok {
+				continue // {include-group = ...}; the included group is listed on its own
+			}
+			if r, ok := parsePEP508(req); ok {
+				out = append(out, pyDeclared{pyRequirement: r, Dev: true, Group: group})
+			}
+		}
+	}
+
+	poetry := pp.Tool.Poetry
+	out = append(out, poetryDeclared(poetry.Dependencies, false, "")...)
+	out = append(out, poetryDeclared(poetry.DevDependencies, true, "dev")...)
+	for _, group := range sortedKeys(poetry.Group) {
+		out = append(out, poetryDeclared(poetry.Group[group].Dependencies, group != "main", group)...)
+	}
+	return out, true
+}
+
+// readPipfile returns the [packages] and [dev-packages] of a Pipfile.
+func readPipfile(dir string) ([]pyDeclared, bool) {
+	b, err := os.ReadFile(filepath.Join(dir, "Pipfile"))
+	if err != nil {
+		return nil, false
+	}
+	var pf pipfile
+	if toml.Unmarshal(b, &pf) != nil {
+		return nil, false
+	}
+	out := poetryDeclared(pf.Packages, false, "")
+	out = append(out, poetryDeclared(pf.DevPackages, true, "dev")...)
+	return out, true
+}
+
+// poetryDeclared converts name = "spec" / name = {version = "spec", optional = true}
+// tables, as used by both Poetry and Pipfile, into declared dependencies.
+func poetryDeclared(table map[string]any, dev bool, group string) []pyDeclared {
+	var out []pyDeclared
+	for _, name := range sortedKeys(table) {
+		if strings.EqualFold(name, "python") {
+			continue // interpreter constraint, not a package
+		}
+		d := pyDeclared{Dev: dev, Group: group}
+		d.Name = normalizePyName(name)
+		switch v := table[name].(type) {
+		case string:
+			d.Spec = v
+		case map[string]any:
+			d.Spec, _ = v["version"].(string)
+			d.Optional, _ = v["optional"].(bool)
+			d.Markers, _ = v["markers"].(string)
+			if git, ok := v["git"].(string); ok {
+				d.URL, d.Kind = git, "vcs"
+			} else if p, ok := v["path"].(string); ok {
+				d.URL, d.Kind = p, "path"
+				if editable, _ := v["editable"].(bool); editable || v["develop"] == true {
+					d.Kind = "editable"
+				}
+			}
+		}
+		if d.Spec == "*" {
+			d.Spec = ""
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// declaredRefs reports manifest dependencies as-is when there is no lockfile.
+func declaredRefs(declared []pyDeclared, source string) []PackageRef {
+	var refs []PackageRef
+	for _, d := range declared {
+		if d.Name == "" {
+			continue
+		}
+		ref := PackageRef{
+			Ecosystem: "python",
+			Name:      d.Name,
+			Version:   d.version(),
+			Source:    source,
+			Direct:    true,
+			Dev:       d.Dev,
+			Optional:  d.Optional,
+			Extras:    d.Extras,
+			Markers:   d.Markers,
+			Kind:      d.Kind,
+		}
+		if d.Group != "" {
+			ref.Groups = []string{d.Group}
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+var pyNameSepRe = regexp.MustCompile(`[-_.]+`)
+
+// normalizePyName applies PEP 503 name normalisation.
+func normalizePyName(name string) string {
+	return pyNameSepRe.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "-")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,45 +1,34 @@
 package deps
 
 import (
-	"os"
 	"path/filepath"
-	"strings"
 )
 
 func DiscoverPythonReqs(dir string) []PackageRef {
-	var refs []PackageRef
-	paths := []string{"requirements.txt", "requirements-dev.txt", "pyproject.toml", "Pipfile"}
+	paths := []string{"requirements.txt", "requirements-dev.txt"}
+	reqs := newReqFileParser(dir)
 	for _, f := range paths {
-		p := filepath.Join(dir, f)
-		b, err := os.ReadFile(p)
-		if err != nil {
-			continue
-		}
-		// super basic extraction: lines like "requests==2.31.0"
-		if strings.HasSuffix(f, ".txt") {
-			for _, ln := range strings.Split(string(b), "\n") {
-				ln = strings.TrimSpace(ln)
-				if ln == "" || strings.HasPrefix(ln, "#") {
-					continue
-				}
-				name, ver := splitAny(ln, []string{"==", ">=", "<=", "~=", ">", "<"})
-				if name != "" {
-					refs = append(refs, PackageRef{Ecosystem: "python", Name: name, Version: ver, Source: f})
-				}
-			}
-		} else {
-			refs = append(refs, PackageRef{Ecosystem: "python", Name: "(see file)", Version: "", Source: f})
-		}
+		reqs.parse(filepath.Join(dir, f), false)
 	}
-	return refs
-}
+	refs := reqs.refs()
 
-func splitAny(s string, seps []string) (string, string) {
-	for _, sep := range seps {
-		if idx := strings.Index(s, sep); idx >= 0 {
-			return strings.TrimSpace(s[:idx]), strings.TrimSpace(s[idx+len(sep):])
-		}
+	// Lockfiles carry resolved versions; the manifests next to them are only
+	// used to tell direct dependencies apart. Without a lockfile the declared
+	// dependencies are reported as written.
+	pyproject, hasPyproject := readPyproject(dir)
+	poetry, hasPoetry := discoverPoetryLock(dir, pyproject)
+	refs = append(refs, poetry...)
+	uv, hasUv := discoverUvLock(dir)
+	refs = append(refs, uv...)
+	if hasPyproject && !hasPoetry && !hasUv {
+		refs = append(refs, declaredRefs(pyproject, "pyproject.toml")...)
 	}
-	// bare name
-	return strings.TrimSpace(s), ""
+
+	pipfile, hasPipfile := readPipfile(dir)
+	pipenv, hasPipenv := discoverPipfileLock(dir, pipfile)
+	refs = append(refs, pipenv...)
+	if hasPipfile && !hasPipenv {
+		refs = append(refs, declaredRefs(pipfile, "Pipfile")...)
+	}
+	return refs
 }
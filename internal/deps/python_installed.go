@@ -0,0 +1,114 @@
+package deps
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DiscoverSitePackages lists the Python distributions installed in the
+// virtualenvs directly below dir, or in dir itself when it is a
+// site-packages directory, from their *.dist-info/METADATA (or
+// *.egg-info/PKG-INFO). A distribution whose RECORD lists files that are
+// no longer there is flagged as incomplete.
+func DiscoverSitePackages(dir string) []PackageRef {
+	var roots []string
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.dist-info")); len(matches) > 0 {
+		roots = append(roots, dir)
+	}
+	venvs, _ := filepath.Glob(filepath.Join(dir, "*", "pyvenv.cfg"))
+	for _, cfg := range venvs {
+		venv := filepath.Dir(cfg)
+		for _, pattern := range []string{"lib/python*/site-packages", "lib64/python*/site-packages", "Lib/site-packages"} {
+			matches, _ := filepath.Glob(filepath.Join(venv, filepath.FromSlash(pattern)))
+			roots = append(roots, matches...)
+		}
+	}
+
+	var refs []PackageRef
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		real, err := filepath.EvalSymlinks(root)
+		if err != nil || seen[real] { // lib64 is usually a link to lib
+			continue
+		}
+		seen[real] = true
+
+		metas, _ := filepath.Glob(filepath.Join(root, "*.dist-info", "METADATA"))
+		eggs, _ := filepath.Glob(filepath.Join(root, "*.egg-info", "PKG-INFO"))
+		for _, meta := range append(metas, eggs...) {
+			name, version := readPyMetadata(meta)
+			if name == "" {
+				continue
+			}
+			info := filepath.Dir(meta)
+			ref := PackageRef{
+				Ecosystem: "python",
+				Name:      name,
+				Version:   version,
+				Source:    relSource(dir, meta),
+				Location:  relSource(dir, info),
+			}
+			if missing := missingRecordFiles(root, filepath.Join(info, "RECORD")); missing > 0 {
+				ref.Mismatch = fmt.Sprintf("incomplete install: %d of the files in RECORD missing", missing)
+			}
+			refs = append(refs, ref)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Location < refs[j].Location })
+	return refs
+}
+
+// readPyMetadata reads Name and Version from the header of a core metadata
+// file (METADATA, PKG-INFO).
+func readPyMetadata(path string) (name, version string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			break // the description follows the headers
+		}
+		if v, ok := strings.CutPrefix(line, "Name:"); ok && name == "" {
+			name = strings.TrimSpace(v)
+		} else if v, ok := strings.CutPrefix(line, "Version:"); ok && version == "" {
+			version = strings.TrimSpace(v)
+		}
+	}
+	return name, version
+}
+
+// missingRecordFiles counts the files a RECORD lists (relative to the
+// site-packages directory) that do not exist. A missing RECORD counts as
+// nothing missing.
+func missingRecordFiles(root, record string) int {
+	f, err := os.Open(record)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return 0
+	}
+	missing := 0
+	for _, row := range rows {
+		if len(row) == 0 || row[0] == "" || strings.HasSuffix(row[0], ".pyc") {
+			continue // bytecode caches are often left out or cleaned up
+		}
+		if _, err := os.Lstat(filepath.Join(root, filepath.FromSlash(row[0]))); err != nil {
+			missing++
+		}
+	}
+	return missing
+}
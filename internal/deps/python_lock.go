@@ -0,0 +1,261 @@
+package deps
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+type poetryLock struct {
+	Package []struct {
+		Name         string         `toml:"name"`
+		Version      string         `toml:"version"`
+		Category     string         `toml:"category"` // poetry < 1.5
+		Groups       []string       `toml:"groups"`   // poetry >= 2.0
+		Optional     bool           `toml:"optional"`
+		Dependencies map[string]any `toml:"dependencies"`
+		Files        []struct {
+			Hash string `toml:"hash"`
+		} `toml:"files"`
+	} `toml:"package"`
+}
+
+type uvDep struct {
+	Name    string `toml:"name"`
+	Version string `toml:"version"`
+}
+
+type uvLock struct {
+	Package []struct {
+		Name                 string             `toml:"name"`
+		Version              string             `toml:"version"`
+		Source               map[string]any     `toml:"source"`
+		Dependencies         []uvDep            `toml:"dependencies"`
+		OptionalDependencies map[string][]uvDep `toml:"optional-dependencies"`
+		DevDependencies      map[string][]uvDep `toml:"dev-dependencies"`
+		Sdist                struct {
+			Hash string `toml:"hash"`
+		} `toml:"sdist"`
+	} `toml:"package"`
+}
+
+type pipfileLockEntry struct {
+	Version string   `json:"version"`
+	Hashes  []string `json:"hashes"`
+}
+
+type pipfileLock struct {
+	Default map[string]pipfileLockEntry `json:"default"`
+	Develop map[string]pipfileLockEntry `json:"develop"`
+}
+
+// discoverPoetryLock resolves poetry.lock, using the dependencies declared
+// in pyproject.toml to tell direct dependencies from transitive ones.
+func discoverPoetryLock(dir string, declared []pyDeclared) ([]PackageRef, bool) {
+	b, err := os.ReadFile(filepath.Join(dir, "poetry.lock"))
+	if err != nil {
+		return nil, false
+	}
+	var lock poetryLock
+	if toml.Unmarshal(b, &lock) != nil {
+		return nil, false
+	}
+
+	g := newLockGraph()
+	for _, p := range lock.Package {
+		name := normalizePyName(p.Name)
+		n := &lockNode{ref: PackageRef{
+			Ecosystem: "python",
+			Name:      name,
+			Version:   p.Version,
+			Source:    "poetry.lock",
+			Optional:  p.Optional,
+			Dev:       p.Category == "dev",
+		}}
+		if len(p.Files) > 0 {
+			n.ref.Integrity = p.Files[0].Hash
+		}
+		for _, group := range p.Groups {
+			if group == "main" {
+				continue
+			}
+			n.ref.Groups = append(n.ref.Groups, group)
+		}
+		if len(p.Groups) > 0 && !containsString(p.Groups, "main") {
+			n.ref.Dev = true
+		}
+		for dep, spec := range p.Dependencies {
+			optional := false
+			if t, ok := spec.(map[string]any); ok {
+				optional, _ = t["optional"].(bool)
+			}
+			n.deps = append(n.deps, lockEdge{key: normalizePyName(dep), optional: optional})
+		}
+		if _, dup := g.nodes[name]; !dup {
+			g.add([]string{name}, n)
+		}
+	}
+
+	prodRoots, devRoots := declaredRoots(declared)
+	return g.refs(prodRoots, devRoots), true
+}
+
+// uvWorkspaceMembers returns the directories of the editable and virtual
+// packages of uv.lock other than the project itself: workspace members whose
+// dependencies the lockfile already resolves.
+func uvWorkspaceMembers(dir string) []string {
+	b, err := os.ReadFile(filepath.Join(dir, "uv.lock"))
+	if err != nil {
+		return nil
+	}
+	var lock uvLock
+	if toml.Unmarshal(b, &lock) != nil {
+		return nil
+	}
+	var dirs []string
+	for _, p := range lock.Package {
+		for _, key := range []string{"editable", "virtual"} {
+			if rel, ok := p.Source[key].(string); ok && rel != "." && !filepath.IsAbs(rel) {
+				dirs = append(dirs, filepath.Join(dir, filepath.FromSlash(rel)))
+			}
+		}
+	}
+	return dirs
+}
+
+// discoverUvLock resolves uv.lock. The project (and any workspace members)
+// appear as editable or virtual packages whose dependency lists are the roots.
+func discoverUvLock(dir string) ([]PackageRef, bool) {
+	b, err := os.ReadFile(filepath.Join(dir, "uv.lock"))
+	if err != nil {
+		return nil, false
+	}
+	var lock uvLock
+	if toml.Unmarshal(b, &lock) != nil {
+		return nil, false
+	}
+
+	uvKey := func(d uvDep) string {
+		if d.Version != "" {
+			return normalizePyName(d.Name) + "@" + d.Version
+		}
+		return normalizePyName(d.Name)
+	}
+
+	g := newLockGraph()
+	var prodRoots, extraRoots, devRoots []string
+	groupRoots := make(map[string][]string)
+	for _, p := range lock.Package {
+		name := normalizePyName(p.Name)
+		_, editable := p.Source["editable"]
+		_, virtual := p.Source["virtual"]
+		if editable || virtual {
+			for _, d := range p.Dependencies {
+				prodRoots = append(prodRoots, uvKey(d))
+			}
+			for extra, list := range p.OptionalDependencies {
+				for _, d := range list {
+					extraRoots = append(extraRoots, uvKey(d))
+					groupRoots[extra] = append(groupRoots[extra], uvKey(d))
+				}
+			}
+			for group, list := range p.DevDependencies {
+				for _, d := range list {
+					devRoots = append(devRoots, uvKey(d))
+					groupRoots[group] = append(groupRoots[group], uvKey(d))
+				}
+			}
+			continue
+		}
+
+		n := &lockNode{ref: PackageRef{
+			Ecosystem: "python",
+			Name:      name,
+			Version:   p.Version,
+			Source:    "uv.lock",
+			Integrity: p.Sdist.Hash,
+		}}
+		for _, d := range p.Dependencies {
+			n.deps = append(n.deps, lockEdge{key: uvKey(d)})
+		}
+		for _, list := range p.OptionalDependencies {
+			for _, d := range list {
+				n.deps = append(n.deps, lockEdge{key: uvKey(d), optional: true})
+			}
+		}
+		keys := []string{name + "@" + p.Version}
+		if _, dup := g.nodes[name]; !dup {
+			keys = append(keys, name)
+		}
+		g.add(keys, n)
+	}
+
+	// only reachable through an extra: optional for consumers of the project
+	viaMain := g.reachable(prodRoots)
+	for n := range g.reachable(extraRoots) {
+		n.ref.Optional = n.ref.Optional || !viaMain[n]
+	}
+	for _, group := range sortedKeys(groupRoots) {
+		for n := range g.reachable(groupRoots[group]) {
+			n.ref.Groups = append(n.ref.Groups, group)
+		}
+	}
+	return g.refs(append(prodRoots, extraRoots...), devRoots), true
+}
+
+// discoverPipfileLock reads the default and develop sections of Pipfile.lock.
+// The lockfile is flat, so only the Pipfile tells which entries are direct.
+func discoverPipfileLock(dir string, declared []pyDeclared) ([]PackageRef, bool) {
+	b, err := os.ReadFile(filepath.Join(dir, "Pipfile.lock"))
+	if err != nil {
+		return nil, false
+	}
+	var lock pipfileLock
+	if json.Unmarshal(b, &lock) != nil {
+		return nil, false
+	}
+
+	direct := make(map[string]bool)
+	for _, d := range declared {
+		direct[d.Name] = true
+	}
+
+	var refs []PackageRef
+	add := func(section map[string]pipfileLockEntry, dev bool) {
+		for _, name := range sortedKeys(section) {
+			e := section[name]
+			if _, inDefault := lock.Default[name]; dev && inDefault {
+				continue // needed at runtime as well
+			}
+			ref := PackageRef{
+				Ecosystem: "python",
+				Name:      normalizePyName(name),
+				Version:   strings.TrimPrefix(e.Version, "=="),
+				Source:    "Pipfile.lock",
+				Dev:       dev,
+			}
+			ref.Direct = direct[ref.Name]
+			if len(e.Hashes) > 0 {
+				ref.Integrity = e.Hashes[0]
+			}
+			refs = append(refs, ref)
+		}
+	}
+	add(lock.Default, false)
+	add(lock.Develop, true)
+	return refs, true
+}
+
+func declaredRoots(declared []pyDeclared) (prodRoots, devRoots []string) {
+	for _, d := range declared {
+		if d.Dev {
+			devRoots = append(devRoots, d.Name)
+		} else {
+			prodRoots = append(prodRoots, d.Name)
+		}
+	}
+	return prodRoots, devRoots
+}
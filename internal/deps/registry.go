@@ -0,0 +1,424 @@
+package deps
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// Ecosystem describes a package ecosystem for reports and graphs.
+type Ecosystem struct {
+	ID    string // PackageRef.Ecosystem and database package type ("npm", "maven")
+	Name  string // report heading ("Maven / Gradle")
+	Label string // graph legend entry ("Maven Dependency")
+	Color string // graph node colour
+	Empty string // shown in the report when nothing was found; without it the section is left out
+}
+
+// Options carries the settings individual discoverers need.
+type Options struct {
+	MavenRepo string   // local Maven repository for transitive resolution ("" to disable)
+	Include   []string // directory globs to discover in (relative to the scanned root, "**" allowed); all when empty
+	Exclude   []string // directory globs to skip, with everything below them
+
+	root    string          // the directory Collection.Discover scans, which bounds lookups of shared files above a project
+	visited map[string]bool // the directories below root it visits, so a discoverer can leave out projects it finds elsewhere
+	goBuild *GoBuild        // where DiscoverGo records what is not a package
+}
+
+// Discoverer finds the packages of one ecosystem in a project directory.
+// Several discoverers may report the same ecosystem (Maven and Gradle).
+type Discoverer interface {
+	Ecosystem() Ecosystem
+	Detect(dir string) bool
+	Discover(dir string, opts Options) []PackageRef
+}
+
+// NestedDiscoverer is implemented by discoverers whose manifests pull in
+// projects in other directories (Maven modules, Gradle subprojects, Cargo
+// workspace members). Recursive discovery does not run the discoverer again
+// in the directories Covers returns, or below them.
+type NestedDiscoverer interface {
+	Discoverer
+	Covers(dir string) []string
+}
+
+// InstalledDiscoverer is implemented by discoverers that report what is
+// installed on disk (node_modules, site-packages, packaged JARs) rather than
+// what a manifest or lockfile asks for. Recursive discovery reconciles their
+// findings with the lockfile data of the same ecosystem: an installed
+// package that matches a lockfile entry only sets its Location, others are
+// reported with PackageRef.Mismatch set.
+type InstalledDiscoverer interface {
+	Discoverer
+	Installed() bool
+}
+
+var (
+	goEcosystem       = Ecosystem{"go", "Go modules", "Go Module", "#00ADD8", "No go.mod detected (or not parsed)."}
+	npmEcosystem      = Ecosystem{"npm", "NPM", "NPM Package", "#CB3837", "No npm lockfile detected (or not parsed)."}
+	pythonEcosystem   = Ecosystem{"python", "Python", "Python Package", "#3776AB", "No python dependency files detected (or not parsed)."}
+	mavenEcosystem    = Ecosystem{"maven", "Maven / Gradle", "Maven Dependency", "#B07219", "No pom.xml or Gradle build detected (or not parsed)."}
+	cargoEcosystem    = Ecosystem{"cargo", "Cargo", "Cargo Crate", "#DEA584", "No Cargo.toml detected (or not parsed)."}
+	nugetEcosystem    = Ecosystem{"nuget", "NuGet", "NuGet Package", "#004880", "No .NET project files detected (or not parsed)."}
+	composerEcosystem = Ecosystem{"composer", "Composer", "Composer Package", "#8892BF", "No composer.lock detected (or not parsed)."}
+	gemEcosystem      = Ecosystem{"gem", "Ruby gems", "Ruby Gem", "#CC342D", "No Gemfile.lock detected (or not parsed)."}
+
+	// what the project is built, tested and deployed with
+	actionsEcosystem   = Ecosystem{"github-actions", "GitHub Actions", "GitHub Action", "#2088FF", "No GitHub Actions workflows detected."}
+	terraformEcosystem = Ecosystem{"terraform", "Terraform", "Terraform Provider / Module", "#7B42BC", "No Terraform configuration detected."}
+	helmEcosystem      = Ecosystem{"helm", "Helm charts", "Helm Chart", "#0F1689", "No Helm chart detected."}
+	dockerEcosystem    = Ecosystem{"docker", "Container base images", "Base Image", "#2496ED", "No Dockerfile or Containerfile detected."}
+
+	// code pulled in without a package manager; usually there is none
+	submoduleEcosystem = Ecosystem{"git-submodule", "Git submodules", "Git Submodule", "#F05032", ""}
+	vendoredEcosystem  = Ecosystem{"vendored", "Vendored code", "Vendored Code", "#6E7781", ""}
+
+	// distro packages only exist in root filesystems, so their sections are
+	// left out of reports on source trees
+	debEcosystem = Ecosystem{"deb", "Debian packages", "Debian Package", "#A80030", ""}
+	apkEcosystem = Ecosystem{"apk", "Alpine packages", "Alpine Package", "#0D597F", ""}
+	rpmEcosystem = Ecosystem{"rpm", "RPM packages", "RPM Package", "#EE0000", ""}
+)
+
+// registry holds the discoverers in the order their ecosystems are reported.
+var registry = []Discoverer{
+	fileDiscoverer{
+		ecosystem: goEcosystem,
+		markers:   []string{"go.work", "go.mod"},
+		discover:  DiscoverGo,
+		covers:    goWorkspaceDirs,
+	},
+	fileDiscoverer{
+		ecosystem: npmEcosystem,
+		markers:   []string{"package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverNpm(dir) },
+	},
+	fileDiscoverer{
+		ecosystem: npmEcosystem,
+		markers:   []string{"node_modules"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverNodeModules(dir) },
+		installed: true,
+	},
+	fileDiscoverer{
+		ecosystem: pythonEcosystem,
+		markers:   []string{"requirements.txt", "requirements-dev.txt", "pyproject.toml", "poetry.lock", "uv.lock", "Pipfile", "Pipfile.lock"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverPythonReqs(dir) },
+		covers:    uvWorkspaceMembers,
+	},
+	fileDiscoverer{
+		// virtualenvs are skipped by the walk, so they are found from their parent
+		ecosystem: pythonEcosystem,
+		markers:   []string{"*/pyvenv.cfg", "*.dist-info"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverSitePackages(dir) },
+		installed: true,
+	},
+	fileDiscoverer{
+		ecosystem: mavenEcosystem,
+		markers:   []string{"pom.xml"},
+		discover:  func(dir string, opts Options) []PackageRef { return DiscoverMaven(dir, opts.MavenRepo) },
+		covers:    mavenModuleDirs,
+	},
+	fileDiscoverer{
+		ecosystem: mavenEcosystem,
+		markers:   []string{"settings.gradle*", "build.gradle*", "gradle.lockfile", "gradle/libs.versions.toml"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverGradle(dir) },
+		covers:    gradleSubprojectDirs,
+	},
+	fileDiscoverer{
+		ecosystem: mavenEcosystem,
+		markers:   []string{"*.[jwe]ar", "target/*.[jwe]ar", "build/libs/*.[jwe]ar"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverJars(dir) },
+		covers:    jarCovers,
+		installed: true,
+	},
+	fileDiscoverer{
+		ecosystem: cargoEcosystem,
+		markers:   []string{"Cargo.toml"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverCargo(dir) },
+		covers:    cargoMemberDirs,
+	},
+	fileDiscoverer{
+		ecosystem: nugetEcosystem,
+		markers:   []string{"*.sln", "*.slnx", "*.csproj", "*.fsproj", "*.vbproj"},
+		discover:  DiscoverNuget,
+		covers:    nugetSolutionDirs,
+	},
+	fileDiscoverer{
+		ecosystem: composerEcosystem,
+		markers:   []string{"composer.lock"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverComposer(dir) },
+	},
+	fileDiscoverer{
+		ecosystem: gemEcosystem,
+		markers:   []string{"Gemfile.lock"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverGems(dir) },
+	},
+	fileDiscoverer{
+		ecosystem: actionsEcosystem,
+		markers:   []string{".github/workflows/*.y*ml", ".github/actions/*/action.y*ml", "action.y*ml"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverGitHubActions(dir) },
+	},
+	fileDiscoverer{
+		ecosystem: terraformEcosystem,
+		markers:   []string{".terraform.lock.hcl", "*.tf"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverTerraform(dir) },
+	},
+	fileDiscoverer{
+		ecosystem: helmEcosystem,
+		markers:   []string{"Chart.yaml", "requirements.yaml"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverHelm(dir) },
+	},
+	fileDiscoverer{
+		ecosystem: dockerEcosystem,
+		markers:   []string{"Dockerfile", "Containerfile", "Dockerfile.*", "Containerfile.*", "*.Dockerfile", "*.dockerfile", "*.Containerfile"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverDockerfiles(dir) },
+	},
+	fileDiscoverer{
+		ecosystem: submoduleEcosystem,
+		markers:   []string{".gitmodules"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverSubmodules(dir) },
+	},
+	fileDiscoverer{
+		ecosystem: vendoredEcosystem,
+		markers:   vendorDirs,
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverVendored(dir) },
+	},
+	fileDiscoverer{
+		ecosystem: debEcosystem,
+		markers:   []string{"var/lib/dpkg/status", "var/lib/dpkg/status.d"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverDpkg(dir) },
+	},
+	fileDiscoverer{
+		ecosystem: apkEcosystem,
+		markers:   []string{"lib/apk/db/installed"},
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverApk(dir) },
+	},
+	fileDiscoverer{
+		ecosystem: rpmEcosystem,
+		markers:   rpmdbPaths,
+		discover:  func(dir string, _ Options) []PackageRef { return DiscoverRpm(dir) },
+	},
+}
+
+// Register adds a discoverer after the built-in ones.
+func Register(d Discoverer) {
+	registry = append(registry, d)
+}
+
+// Discoverers returns the registered discoverers in report order.
+func Discoverers() []Discoverer {
+	return append([]Discoverer(nil), registry...)
+}
+
+// Ecosystems returns each registered ecosystem once, in report order.
+func Ecosystems() []Ecosystem {
+	var out []Ecosystem
+	seen := make(map[string]bool)
+	for _, d := range registry {
+		if e := d.Ecosystem(); !seen[e.ID] {
+			seen[e.ID] = true
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// LookupEcosystem returns the registered ecosystem with the given ID.
+func LookupEcosystem(id string) (Ecosystem, bool) {
+	for _, e := range Ecosystems() {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return Ecosystem{}, false
+}
+
+// fileDiscoverer adapts a Discover* function to the Discoverer interface. The
+// ecosystem is detected by marker files, glob patterns relative to the
+// project directory.
+type fileDiscoverer struct {
+	ecosystem Ecosystem
+	markers   []string
+	discover  func(dir string, opts Options) []PackageRef
+	covers    func(dir string) []string // nested project directories, if any
+	installed bool                      // reports installed packages, see InstalledDiscoverer
+}
+
+func (d fileDiscoverer) Ecosystem() Ecosystem { return d.ecosystem }
+
+func (d fileDiscoverer) Detect(dir string) bool {
+	for _, m := range d.markers {
+		if matches, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(m))); len(matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (d fileDiscoverer) Discover(dir string, opts Options) []PackageRef {
+	return d.discover(dir, opts)
+}
+
+func (d fileDiscoverer) Installed() bool { return d.installed }
+
+func (d fileDiscoverer) Covers(dir string) []string {
+	if d.covers == nil {
+		return nil
+	}
+	return d.covers(dir)
+}
+
+// EcosystemPackages holds the packages found for one ecosystem.
+type EcosystemPackages struct {
+	Ecosystem Ecosystem
+	Packages  []PackageRef
+	Go        *GoBuild // Go only: main modules, workspaces and the module behind each package
+}
+
+// Collection holds discovered packages grouped by ecosystem.
+type Collection []EcosystemPackages
+
+// Discover walks the tree below root (see projectDirs) and runs every
+// registered discoverer in each directory where it detects its ecosystem,
+// attributing what it finds to that directory through PackageRef.Subproject.
+// Packages their discoverer gave no Scope are classified with ClassifyScope.
+// Installed packages are reconciled with the lockfile data afterwards (see
+// InstalledDiscoverer). Every registered ecosystem gets an entry, even an
+// empty one, and entries are kept in registry order.
+func (c *Collection) Discover(root string, opts Options) {
+	for _, d := range registry {
+		c.entry(d.Ecosystem().ID)
+	}
+
+	dirs := projectDirs(root, opts)
+	opts.root, opts.visited, opts.goBuild = root, make(map[string]bool), c.goBuild()
+	for _, rel := range dirs {
+		opts.visited[rel] = true
+	}
+	covered := make([][]string, len(registry))
+	var installed []PackageRef
+	for _, rel := range dirs {
+		dir := filepath.Join(root, filepath.FromSlash(rel))
+		for i, d := range registry {
+			if within(rel, covered[i]) || !d.Detect(dir) {
+				continue
+			}
+			refs := d.Discover(dir, opts)
+			for j := range refs {
+				refs[j].Subproject = joinSubproject(rel, refs[j].Subproject)
+				if refs[j].Scope == "" {
+					refs[j].Scope = ClassifyScope(refs[j])
+				}
+				if refs[j].Location != "" {
+					refs[j].Location = joinSubproject(rel, refs[j].Location)
+				}
+			}
+			if id, ok := d.(InstalledDiscoverer); ok && id.Installed() {
+				installed = append(installed, refs...)
+			} else {
+				e := c.entry(d.Ecosystem().ID)
+				e.Packages = append(e.Packages, refs...)
+			}
+
+			if nd, ok := d.(NestedDiscoverer); ok {
+				for _, sub := range nd.Covers(dir) {
+					if r, err := filepath.Rel(root, sub); err == nil {
+						covered[i] = append(covered[i], filepath.ToSlash(r))
+					}
+				}
+			}
+		}
+	}
+	c.reconcileInstalled(installed)
+
+	order := make(map[string]int)
+	for i, e := range Ecosystems() {
+		order[e.ID] = i + 1
+	}
+	rank := func(id string) int {
+		if r, ok := order[id]; ok {
+			return r
+		}
+		return len(order) + 1
+	}
+	sort.SliceStable(*c, func(i, j int) bool { return rank((*c)[i].Ecosystem.ID) < rank((*c)[j].Ecosystem.ID) })
+}
+
+// Add files packages under their PackageRef.Ecosystem, classifying their
+// scope if it is not set.
+func (c *Collection) Add(pkgs ...PackageRef) {
+	for _, p := range pkgs {
+		if p.Scope == "" {
+			p.Scope = ClassifyScope(p)
+		}
+		e := c.entry(p.Ecosystem)
+		e.Packages = append(e.Packages, p)
+	}
+}
+
+// AddGoModules adds a build list read from elsewhere than go.mod, such as
+// the build info of Go binaries (see GoBinaryModules): main modules are
+// recorded in the GoBuild, the other modules added as packages located in
+// their Subproject.
+func (c *Collection) AddGoModules(modules []GoModule) {
+	b := c.goBuild()
+	for _, m := range modules {
+		b.add(m)
+		if p, ok := goModuleRef(m); ok {
+			p.Location = m.Subproject
+			c.Add(p)
+		}
+	}
+}
+
+// GoBuild returns what Go discovery recorded besides packages; it is empty
+// when nothing was.
+func (c Collection) GoBuild() *GoBuild {
+	for _, e := range c {
+		if e.Go != nil {
+			return e.Go
+		}
+	}
+	return &GoBuild{}
+}
+
+func (c *Collection) goBuild() *GoBuild {
+	e := c.entry(goEcosystem.ID)
+	if e.Go == nil {
+		e.Go = &GoBuild{}
+	}
+	return e.Go
+}
+
+// Get returns the packages of one ecosystem.
+func (c Collection) Get(id string) []PackageRef {
+	for _, e := range c {
+		if e.Ecosystem.ID == id {
+			return e.Packages
+		}
+	}
+	return nil
+}
+
+// Len returns the number of packages across all ecosystems.
+func (c Collection) Len() int {
+	n := 0
+	for _, e := range c {
+		n += len(e.Packages)
+	}
+	return n
+}
+
+func (c *Collection) entry(id string) *EcosystemPackages {
+	for i := range *c {
+		if (*c)[i].Ecosystem.ID == id {
+			return &(*c)[i]
+		}
+	}
+	e, ok := LookupEcosystem(id)
+	if !ok {
+		e = Ecosystem{ID: id, Name: id, Label: id}
+	}
+	*c = append(*c, EcosystemPackages{Ecosystem: e})
+	return &(*c)[len(*c)-1]
+}
@@ -0,0 +1,322 @@
+package deps
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// pyRequirement is a parsed PEP 508 requirement or requirements-file entry.
+type pyRequirement struct {
+	Name    string
+	Extras  []string
+	Spec    string // version specifier, e.g. ">=2.0,<3"
+	URL     string // direct reference, VCS URL or local path
+	Markers string // environment marker, e.g. python_version < "3.8"
+	Kind    string // "" for index packages, otherwise "editable", "vcs", "url" or "path"
+	Hash    string
+}
+
+// version is what gets reported for the requirement: the pinned version when
+// there is exactly one, otherwise the specifier (or VCS revision) as written.
+func (r pyRequirement) version() string {
+	if strings.HasPrefix(r.Spec, "==") && !strings.Contains(r.Spec, ",") && !strings.Contains(r.Spec, "*") {
+		return strings.TrimLeft(r.Spec, "=")
+	}
+	if r.Spec == "" && pyURLKind(r.URL) == "vcs" {
+		if at := strings.LastIndex(r.URL, "@"); at > strings.Index(r.URL, "://") {
+			return strings.SplitN(r.URL[at+1:], "#", 2)[0]
+		}
+	}
+	return r.Spec
+}
+
+var (
+	pyReqNameRe = regexp.MustCompile(`^([A-Za-z0-9](?:[A-Za-z0-9._-]*[A-Za-z0-9])?)`)
+	pyEggRe     = regexp.MustCompile(`[#&]egg=([A-Za-z0-9][A-Za-z0-9._-]*)`)
+	pyVCSPrefix = []string{"git+", "hg+", "svn+", "bzr+"}
+)
+
+// parsePEP508 parses strings like
+//
+//	requests[security,socks] >=2.0,<3 ; python_version < "3.8"
+//	pip @ https://github.com/pypa/pip/archive/22.0.zip
+func parsePEP508(s string) (pyRequirement, bool) {
+	var r pyRequirement
+	s = strings.TrimSpace(s)
+	m := pyReqNameRe.FindString(s)
+	if m == "" {
+		return r, false
+	}
+	r.Name = normalizePyName(m)
+	rest := strings.TrimSpace(s[len(m):])
+
+	if strings.HasPrefix(rest, "[") {
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return r, false
+		}
+		for _, e := range strings.Split(rest[1:end], ",") {
+			if e = strings.TrimSpace(e); e != "" {
+				r.Extras = append(r.Extras, normalizePyName(e))
+			}
+		}
+		rest = strings.TrimSpace(rest[end+1:])
+	}
+
+	if strings.HasPrefix(rest, "@") {
+		// with a URL the marker separator needs whitespace in front of it
+		rest = strings.TrimSpace(rest[1:])
+		url := rest
+		if i := strings.Index(rest, " ;"); i >= 0 {
+			url, r.Markers = rest[:i], strings.TrimSpace(rest[i+2:])
+		}
+		r.URL = strings.TrimSpace(url)
+		r.Kind = pyURLKind(r.URL)
+		return r, true
+	}
+
+	if i := strings.Index(rest, ";"); i >= 0 {
+		rest, r.Markers = rest[:i], strings.TrimSpace(rest[i+1:])
+	}
+	rest = strings.TrimSpace(rest)
+	if strings.HasPrefix(rest, "(") && strings.HasSuffix(rest, ")") {
+		rest = rest[1 : len(rest)-1]
+	}
+	r.Spec = strings.ReplaceAll(rest, " ", "")
+	return r, true
+}
+
+// parsePipLocation handles requirement-file entries that are not PEP 508
+// strings: bare VCS URLs, archive URLs and local paths (relative to dir),
+// which may end in extras ("-e .[dev]").
+func parsePipLocation(loc, dir string) (pyRequirement, bool) {
+	var extras []string
+	if i := strings.LastIndex(loc, "["); i > 0 && strings.HasSuffix(loc, "]") && !strings.Contains(loc[i:], "/") {
+		for _, e := range strings.Split(loc[i+1:len(loc)-1], ",") {
+			if e = strings.TrimSpace(e); e != "" {
+				extras = append(extras, normalizePyName(e))
+			}
+		}
+		loc = loc[:i]
+	}
+	r := pyRequirement{URL: loc, Kind: pyURLKind(loc), Extras: extras}
+	if m := pyEggRe.FindStringSubmatch(loc); m != nil {
+		r.Name = normalizePyName(m[1])
+		return r, true
+	}
+	base := path.Base(strings.SplitN(strings.SplitN(loc, "#", 2)[0], "?", 2)[0])
+	switch {
+	case strings.HasSuffix(base, ".whl"):
+		// name-version-tags.whl
+		parts := strings.Split(strings.TrimSuffix(base, ".whl"), "-")
+		if len(parts) >= 2 {
+			r.Name, r.Spec = normalizePyName(parts[0]), "=="+parts[1]
+		}
+	case strings.HasSuffix(base, ".tar.gz"), strings.HasSuffix(base, ".zip"):
+		stem := strings.TrimSuffix(strings.TrimSuffix(base, ".tar.gz"), ".zip")
+		if i := strings.LastIndex(stem, "-"); i > 0 {
+			r.Name, r.Spec = normalizePyName(stem[:i]), "=="+stem[i+1:]
+		}
+	case r.Kind == "path":
+		local := strings.TrimPrefix(loc, "file://")
+		if !filepath.IsAbs(local) {
+			local = filepath.Join(dir, local)
+		}
+		r.Name = normalizePyName(filepath.Base(local))
+	}
+	return r, r.Name != "" && r.Name != "-"
+}
+
+func pyURLKind(loc string) string {
+	for _, p := range pyVCSPrefix {
+		if strings.HasPrefix(loc, p) {
+			return "vcs"
+		}
+	}
+	if strings.HasPrefix(loc, "file:") || !strings.Contains(loc, "://") {
+		return "path"
+	}
+	return "url"
+}
+
+// reqFileParser walks pip requirement files, following -r includes and
+// collecting -c constraints, which only pin versions of listed packages.
+type reqFileParser struct {
+	baseDir     string
+	visited     map[string]bool
+	reqs        []PackageRef
+	constraints map[string]pyRequirement
+}
+
+func newReqFileParser(baseDir string) *reqFileParser {
+	return &reqFileParser{
+		baseDir:     baseDir,
+		visited:     make(map[string]bool),
+		constraints: make(map[string]pyRequirement),
+	}
+}
+
+func (p *reqFileParser) parse(file string, constraint bool) {
+	file = filepath.Clean(file)
+	if p.visited[file] {
+		return
+	}
+	p.visited[file] = true
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+	source, err := filepath.Rel(p.baseDir, file)
+	if err != nil {
+		source = filepath.Base(file)
+	}
+	source = filepath.ToSlash(source)
+
+	for _, line := range joinContinuations(string(b)) {
+		line = stripReqComment(line)
+		if line == "" {
+			continue
+		}
+
+		editable := false
+		if strings.HasPrefix(line, "-") {
+			flag, arg := splitReqOption(line)
+			switch flag {
+			case "-r", "--requirement":
+				p.parse(filepath.Join(filepath.Dir(file), arg), constraint)
+				continue
+			case "-c", "--constraint":
+				p.parse(filepath.Join(filepath.Dir(file), arg), true)
+				continue
+			case "-e", "--editable":
+				editable = true
+				line = arg
+			default:
+				continue // index/find-links/global options
+			}
+		}
+
+		// per-requirement options such as --hash follow the requirement itself
+		fields := strings.Fields(line)
+		n := len(fields)
+		for i, f := range fields {
+			if strings.HasPrefix(f, "--") {
+				n = i
+				break
+			}
+		}
+		text := strings.Join(fields[:n], " ")
+		var hash string
+		for i := n; i < len(fields) && hash == ""; i++ {
+			if fields[i] == "--hash" && i+1 < len(fields) {
+				hash = fields[i+1]
+			} else if strings.HasPrefix(fields[i], "--hash=") {
+				hash = strings.TrimPrefix(fields[i], "--hash=")
+			}
+		}
+
+		r, ok := parsePEP508(text)
+		if !ok || editable || (r.URL == "" && pyLooksLikeLocation(text)) {
+			r, ok = parsePipLocation(text, filepath.Dir(file))
+		}
+		if !ok {
+			continue
+		}
+		if editable {
+			r.Kind = "editable"
+		}
+		r.Hash = hash
+
+		if constraint {
+			p.constraints[r.Name] = r
+			continue
+		}
+		p.reqs = append(p.reqs, PackageRef{
+			Ecosystem: "python",
+			Name:      r.Name,
+			Version:   r.version(),
+			Source:    source,
+			Integrity: r.Hash,
+			Direct:    true,
+			Extras:    r.Extras,
+			Markers:   r.Markers,
+			Kind:      r.Kind,
+		})
+	}
+}
+
+// refs returns the collected requirements with constraint pins applied.
+func (p *reqFileParser) refs() []PackageRef {
+	for i := range p.reqs {
+		c, ok := p.constraints[p.reqs[i].Name]
+		if !ok || p.reqs[i].Kind != "" {
+			continue
+		}
+		if v := c.version(); strings.HasPrefix(c.Spec, "==") && v != p.reqs[i].Version {
+			p.reqs[i].Version = v
+			if p.reqs[i].Integrity == "" {
+				p.reqs[i].Integrity = c.Hash
+			}
+		}
+	}
+	return p.reqs
+}
+
+func pyLooksLikeLocation(s string) bool {
+	return strings.Contains(s, "/") || strings.HasPrefix(s, ".") ||
+		strings.HasSuffix(s, ".whl") || strings.HasSuffix(s, ".tar.gz") || strings.HasSuffix(s, ".zip")
+}
+
+// joinContinuations splits a requirements file into logical lines,
+// joining lines that end with a backslash.
+func joinContinuations(content string) []string {
+	var lines []string
+	var cur strings.Builder
+	for _, ln := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimRight(ln, " \t")
+		if strings.HasSuffix(trimmed, "\\") {
+			cur.WriteString(strings.TrimSuffix(trimmed, "\\"))
+			cur.WriteString(" ")
+			continue
+		}
+		cur.WriteString(ln)
+		lines = append(lines, cur.String())
+		cur.Reset()
+	}
+	if cur.Len() > 0 {
+		lines = append(lines, cur.String())
+	}
+	return lines
+}
+
+// stripReqComment removes "# ..." comments; a '#' glued to a URL (#egg=) is kept.
+func stripReqComment(line string) string {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "#") {
+		return ""
+	}
+	for i := 1; i < len(line); i++ {
+		if line[i] == '#' && (line[i-1] == ' ' || line[i-1] == '\t') {
+			return strings.TrimSpace(line[:i])
+		}
+	}
+	return line
+}
+
+// splitReqOption splits "-r file", "-rfile" and "--requirement=file".
+func splitReqOption(line string) (string, string) {
+	if strings.HasPrefix(line, "--") {
+		if flag, arg, ok := strings.Cut(line, "="); ok && !strings.ContainsAny(flag, " \t") {
+			return flag, strings.TrimSpace(arg)
+		}
+		flag, arg, _ := strings.Cut(line, " ")
+		return flag, strings.TrimSpace(arg)
+	}
+	if len(line) < 2 {
+		return line, ""
+	}
+	return line[:2], strings.TrimSpace(line[2:])
+}
@@ -0,0 +1,163 @@
+package deps
+
+import (
+	"strings"
+)
+
+// Scopes a dependency can have, from the one that matters most at runtime
+// to the least.
+const (
+	ScopeRuntime  = "runtime"  // shipped with or loaded by the running software
+	ScopeOptional = "optional" // only when an optional feature or platform needs it
+	ScopeBuild    = "build"    // build, CI and deployment tooling that is not shipped
+	ScopeDev      = "dev"      // development tooling (linters, formatters, docs)
+	ScopeTest     = "test"     // only needed to run tests
+)
+
+// Scopes lists the scopes in order of importance.
+var Scopes = []string{ScopeRuntime, ScopeOptional, ScopeBuild, ScopeDev, ScopeTest}
+
+// ClassifyScope derives a package's scope from what its discoverer
+// recorded: the ecosystem's own scope names (Maven scopes, Gradle
+// configurations, Cargo dependency kinds, Dockerfile stages), groups that
+// name tests (Poetry, PEP 735, Bundler), and the Dev and Optional flags.
+func ClassifyScope(p PackageRef) string {
+	switch p.Ecosystem {
+	case "go":
+		return "" // classifyGoScopes leaves what it cannot place unknown
+	case "maven":
+		if s, ok := jvmScope(p); ok {
+			return s
+		}
+	case "cargo":
+		switch p.NativeScope {
+		case "build":
+			return ScopeBuild
+		case "dev":
+			return ScopeDev
+		}
+	case "nuget":
+		if p.Dev {
+			return ScopeBuild // analyzers and other PrivateAssets="all" packages
+		}
+	case "github-actions", "terraform":
+		return ScopeBuild
+	case "docker":
+		if p.NativeScope != "base image" {
+			return ScopeBuild // build stages and images files are copied from
+		}
+	}
+
+	switch {
+	case testGroups(p.Groups):
+		return ScopeTest
+	case p.Dev:
+		return ScopeDev
+	case p.Optional:
+		return ScopeOptional
+	}
+	return ScopeRuntime
+}
+
+// jvmScope maps a Maven scope or a list of Gradle configurations.
+func jvmScope(p PackageRef) (string, bool) {
+	if p.NativeScope == "" {
+		return "", false
+	}
+	switch p.NativeScope {
+	case "test":
+		return ScopeTest, true
+	case "provided", "system", "import":
+		return ScopeBuild, true // supplied by the runtime or only managing versions, not packaged
+	case "compile", "runtime":
+		if p.Optional {
+			return ScopeOptional, true
+		}
+		return ScopeRuntime, true
+	}
+
+	scope := ""
+	for _, cfg := range strings.Split(p.NativeScope, ",") {
+		lower := strings.ToLower(cfg)
+		var s string
+		switch {
+		case strings.Contains(lower, "test"):
+			s = ScopeTest
+		case lower == "classpath" || strings.Contains(lower, "annotationprocessor") || strings.HasPrefix(lower, "kapt") ||
+			strings.HasPrefix(lower, "ksp") || strings.Contains(lower, "compileonly") || strings.HasSuffix(lower, "compileclasspath"):
+			s = ScopeBuild // buildscript, annotation processors, compile-only
+		case strings.Contains(lower, "runtime") || strings.HasSuffix(lower, "implementation") || strings.HasSuffix(lower, "api") ||
+			lower == "compile" || lower == "default":
+			s = ScopeRuntime
+		default:
+			s = ScopeBuild // checkstyle, detekt, jacoco and other tool configurations
+		}
+		if scope == "" || scopeRank(s) < scopeRank(scope) {
+			scope = s
+		}
+	}
+	return scope, true
+}
+
+// testGroups reports whether a package is only pulled in by groups meant
+// for tests.
+func testGroups(groups []string) bool {
+	if len(groups) == 0 {
+		return false
+	}
+	for _, g := range groups {
+		switch strings.ToLower(g) {
+		case "test", "tests", "testing", "pytest", "spec":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// scopeRank orders scopes by importance; unknown scopes sort last.
+func scopeRank(scope string) int {
+	for i, s := range Scopes {
+		if s == scope {
+			return i
+		}
+	}
+	return len(Scopes)
+}
+
+// WiderScope returns whichever of two scopes matters more, so a package
+// that is both a test and a runtime dependency counts as runtime.
+func WiderScope(a, b string) string {
+	if a == "" || scopeRank(b) < scopeRank(a) {
+		return b
+	}
+	return a
+}
+
+// InScopes reports whether scope is one of scopes; every scope is when
+// scopes is empty.
+func InScopes(scope string, scopes []string) bool {
+	return len(scopes) == 0 || containsString(scopes, scope)
+}
+
+// FilterScopes keeps the packages whose Scope is one of scopes, counting
+// packages without a scope as runtime; every ecosystem keeps its entry.
+func (c Collection) FilterScopes(scopes []string) Collection {
+	if len(scopes) == 0 {
+		return c
+	}
+	out := make(Collection, len(c))
+	for i, e := range c {
+		out[i].Ecosystem, out[i].Go = e.Ecosystem, e.Go
+		for _, p := range e.Packages {
+			scope := p.Scope
+			if scope == "" {
+				scope = ScopeRuntime
+			}
+			if InScopes(scope, scopes) {
+				out[i].Packages = append(out[i].Packages, p)
+			}
+		}
+	}
+	return out
+}
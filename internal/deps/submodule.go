@@ -0,0 +1,28 @@
+package deps
+
+import "sbom-report/internal/git"
+
+// DiscoverSubmodules lists the git submodules of the repository at dir at
+// the commits its HEAD pins them to. Repository is the submodule URL;
+// Location is where it is checked out.
+func DiscoverSubmodules(dir string) []PackageRef {
+	var refs []PackageRef
+	for _, s := range git.GetSubmodules(dir) {
+		name := s.URL
+		if s.Remote.Host != "" && s.Remote.Path != "" {
+			name = s.Remote.Host + "/" + s.Remote.Path
+		}
+		ref := PackageRef{
+			Ecosystem:  "git-submodule",
+			Name:       name,
+			Version:    s.Commit,
+			Source:     ".gitmodules",
+			Location:   s.Path,
+			Repository: s.URL,
+			Direct:     true,
+			Branch:     s.Branch,
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
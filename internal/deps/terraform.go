@@ -0,0 +1,180 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	tfBlockRe = regexp.MustCompile(`^(provider|module)\s+"([^"]+)"\s*\{`)
+	tfAttrRe  = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*)\s*=\s*"([^"]*)"`)
+	tfExactRe = regexp.MustCompile(`^=?\s*v?\d+(\.\d+)*(-[0-9A-Za-z.-]+)?$`)
+)
+
+// DiscoverTerraform lists the providers locked in .terraform.lock.hcl and
+// the modules that the *.tf files of dir call. Locked providers are pinned
+// by their hashes; registry modules need an exact version and git modules
+// a commit SHA as ref to count as pinned. Local modules ("./network") are
+// skipped.
+func DiscoverTerraform(dir string) []PackageRef {
+	var refs []PackageRef
+	lockPath := filepath.Join(dir, ".terraform.lock.hcl")
+	for _, b := range readTerraformBlocks(lockPath) {
+		if b.kind != "provider" {
+			continue
+		}
+		ref := PackageRef{
+			Ecosystem: "terraform",
+			Name:      strings.TrimPrefix(b.label, "registry.terraform.io/"),
+			Version:   b.attrs["version"],
+			Source:    ".terraform.lock.hcl",
+			Direct:    true,
+			RefType:   "provider",
+		}
+		if len(b.hashes) > 0 {
+			ref.Integrity = b.hashes[0]
+		} else {
+			ref.Unpinned = "no hashes in lock file"
+		}
+		refs = append(refs, ref)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.tf"))
+	sort.Strings(files)
+	for _, path := range files {
+		for _, b := range readTerraformBlocks(path) {
+			if b.kind != "module" {
+				continue
+			}
+			if ref, ok := terraformModule(b.attrs["source"], b.attrs["version"]); ok {
+				ref.Source = relSource(dir, path)
+				ref.Groups = []string{b.label}
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// terraformModule describes a module call from its source and version
+// arguments.
+func terraformModule(source, version string) (PackageRef, bool) {
+	if source == "" || strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
+		return PackageRef{}, false
+	}
+	ref := PackageRef{Ecosystem: "terraform", Direct: true, RefType: "module"}
+
+	if terraformRegistrySource(source) {
+		ref.Name = strings.TrimPrefix(source, "registry.terraform.io/")
+		ref.Version = strings.TrimSpace(version)
+		switch {
+		case ref.Version == "":
+			ref.Unpinned = "no version constraint"
+		case !tfExactRe.MatchString(ref.Version):
+			ref.Unpinned = "version constraint " + ref.Version + " is not exact"
+		default:
+			ref.Version = strings.TrimSpace(strings.TrimPrefix(ref.Version, "="))
+		}
+		return ref, true
+	}
+
+	// everything else is fetched by go-getter: git::, github.com/..., https://...zip
+	addr, query, _ := strings.Cut(source, "?")
+	ref.Name = addr
+	ref.Kind = "url"
+	if strings.HasPrefix(addr, "git::") || strings.HasPrefix(addr, "git@") || strings.HasPrefix(addr, "github.com/") || strings.HasPrefix(addr, "bitbucket.org/") {
+		ref.Kind = "vcs"
+	}
+	for _, kv := range strings.Split(query, "&") {
+		if v, ok := strings.CutPrefix(kv, "ref="); ok {
+			ref.Version = v
+		}
+	}
+	switch {
+	case ref.Kind == "url":
+		ref.Unpinned = "archive URL without checksum"
+		if strings.Contains(query, "checksum=") {
+			ref.Unpinned = ""
+		}
+	case ref.Version == "":
+		ref.Unpinned = "no ref, follows the default branch"
+	case !commitSHARe.MatchString(ref.Version):
+		ref.Unpinned = "ref " + ref.Version + " is not a commit SHA"
+	}
+	return ref, true
+}
+
+// terraformRegistrySource reports whether a module source is a registry
+// address, [host/]namespace/name/provider, rather than a go-getter one.
+func terraformRegistrySource(source string) bool {
+	if strings.ContainsAny(source, ":?") || strings.HasPrefix(source, "github.com/") || strings.HasPrefix(source, "bitbucket.org/") {
+		return false
+	}
+	switch strings.Count(source, "/") {
+	case 2:
+		return true
+	case 3:
+		host, _, _ := strings.Cut(source, "/")
+		return strings.Contains(host, ".")
+	}
+	return false
+}
+
+// terraformBlock is a top-level provider or module block with its string
+// arguments.
+type terraformBlock struct {
+	kind, label string
+	attrs       map[string]string
+	hashes      []string // lock file provider hashes
+}
+
+// readTerraformBlocks reads the top-level provider and module blocks of an
+// HCL file. Only string arguments directly inside the block are kept; the
+// parser follows braces and brackets but not heredocs or expressions.
+func readTerraformBlocks(path string) []terraformBlock {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var blocks []terraformBlock
+	var cur *terraformBlock
+	depth := 0
+	inHashes := false
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") || line == "" {
+			continue
+		}
+		if depth == 0 {
+			if m := tfBlockRe.FindStringSubmatch(line); m != nil {
+				blocks = append(blocks, terraformBlock{kind: m[1], label: m[2], attrs: map[string]string{}})
+				cur = &blocks[len(blocks)-1]
+			} else {
+				cur = nil
+			}
+		} else if cur != nil && depth == 1 {
+			if inHashes {
+				if h := strings.Trim(strings.TrimSuffix(line, ","), `"`); h != "]" && h != "" {
+					cur.hashes = append(cur.hashes, h)
+				}
+			} else if m := tfAttrRe.FindStringSubmatch(line); m != nil {
+				cur.attrs[m[1]] = m[2]
+			}
+			if strings.HasPrefix(line, "hashes") && strings.HasSuffix(line, "[") {
+				inHashes = true
+				continue
+			}
+		}
+		if inHashes && strings.HasPrefix(line, "]") {
+			inHashes = false
+		}
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if depth < 0 {
+			depth = 0
+		}
+	}
+	return blocks
+}
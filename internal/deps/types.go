@@ -5,4 +5,42 @@ type PackageRef struct {
 	Name      string
 	Version   string
 	Source    string // file that referenced it
+
+	Integrity   string // lockfile integrity hash, if recorded
+	Path        string // location inside the lockfile tree (e.g. node_modules/a/node_modules/b)
+	Direct      bool   // required by the project itself rather than another package
+	Dev         bool
+	Optional    bool
+	Peer        bool
+	Parents     []string // names of packages that depend on this one
+	Groups      []string // dependency groups or extras that pull it in (e.g. poetry "docs", PEP 621 extras)
+	Extras      []string // extras requested from the package itself (requests[socks])
+	Markers     string   // environment marker the requirement is conditional on
+	Kind        string   // "" for registry packages, otherwise "editable", "vcs", "url" or "path"
+	NativeScope string   // scope as the ecosystem names it (Maven "test", Gradle "compileClasspath,runtimeClasspath")
+	RefType     string   // what is referenced, where an ecosystem has several sorts (GitHub Actions "action", "reusable workflow" or "docker"; Terraform "provider" or "module")
+	Branch      string   // branch a git reference follows when updated (a submodule's .gitmodules branch)
+	Scope       string   // runtime, optional, build, dev or test; see ClassifyScope
+	Location    string   // where it is installed on disk (node_modules/a, .venv/lib/python3.12/site-packages/a-1.0.dist-info, app.jar!/BOOT-INF/lib/b.jar)
+	Mismatch    string   // how the installed package disagrees with the lockfile, if it does
+	Repository  string   // repository the package is fetched from, when Name does not say (Helm chart repository)
+	Unpinned    string   // why the reference can change under the same version (a tag or branch rather than a commit SHA or digest), if it can
+	Licenses    []string // licenses as the package metadata declares them
+	OS          *OSInfo  // set for packages from a distro package database
+
+	// set by Collection.Merge when several sources reported the package
+	SeenIn           []string // every source that reported it
+	VersionConflicts []string // exact versions other sources report for it ("2.30.0 in requirements.txt")
+
+	Subproject string // workspace/subproject path that requires it ("" for the root project)
+}
+
+// OSInfo describes a package installed by the distribution's package manager.
+type OSInfo struct {
+	Distro        string // os-release ID ("debian", "alpine", "rhel"), the purl namespace
+	DistroVersion string // os-release VERSION_ID
+	Arch          string
+	Epoch         string // rpm epoch, when set
+	SourceName    string // source package it was built from (dpkg Source, apk origin, rpm SOURCERPM)
+	SourceVersion string
 }
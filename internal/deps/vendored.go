@@ -0,0 +1,238 @@
+package deps
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// vendorDirs are the directories third-party code is conventionally copied
+// into.
+var vendorDirs = []string{"third_party", "third-party", "thirdparty", "3rdparty", "external", "extern", "vendor"}
+
+// DiscoverVendored lists third-party code copied into the vendor
+// directories of dir (see vendorDirs): each subdirectory with a license
+// file or package metadata of its own. Name, version and license come from
+// README.chromium, package.json, composer.json, Cargo.toml, pyproject.toml
+// or a VERSION file, and the license also from the license text. Vendor
+// directories managed by a package manager (Go modules.txt, Composer,
+// Bundler) and submodule checkouts are skipped.
+func DiscoverVendored(dir string) []PackageRef {
+	var refs []PackageRef
+	for _, vd := range vendorDirs {
+		root := filepath.Join(dir, vd)
+		if fileExists(filepath.Join(root, "modules.txt")) || fileExists(filepath.Join(root, "autoload.php")) || pathExists(filepath.Join(root, "bundle")) {
+			continue
+		}
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			pkgDir := filepath.Join(root, e.Name())
+			if !e.IsDir() || strings.HasPrefix(e.Name(), ".") || pathExists(filepath.Join(pkgDir, ".git")) {
+				continue
+			}
+			if ref, ok := readVendored(dir, pkgDir); ok {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// readVendored describes one vendored directory, or reports false when it
+// carries no license or metadata to identify it by.
+func readVendored(dir, pkgDir string) (PackageRef, bool) {
+	ref := PackageRef{
+		Ecosystem: "vendored",
+		Name:      filepath.Base(pkgDir),
+		Location:  relSource(dir, pkgDir),
+		Direct:    true,
+	}
+	var evidence []string
+	var license string
+	note := func(file string) { evidence = append(evidence, relSource(dir, filepath.Join(pkgDir, file))) }
+	set := func(name, version string) {
+		if name != "" {
+			ref.Name = name
+		}
+		if version != "" && ref.Version == "" {
+			ref.Version = version
+		}
+	}
+
+	if f, ok := readReadmeChromium(filepath.Join(pkgDir, "README.chromium")); ok {
+		note("README.chromium")
+		set(f["Name"], f["Version"])
+		license = f["License"]
+		ref.Repository = f["URL"]
+	}
+	if b, err := os.ReadFile(filepath.Join(pkgDir, "package.json")); err == nil {
+		var pj struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+			License string `json:"license"`
+		}
+		if json.Unmarshal(b, &pj) == nil && pj.Name != "" {
+			note("package.json")
+			set(pj.Name, pj.Version)
+			license = firstNonEmpty(license, pj.License)
+		}
+	}
+	if b, err := os.ReadFile(filepath.Join(pkgDir, "composer.json")); err == nil {
+		var cj struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+			License any    `json:"license"` // string or list
+		}
+		if json.Unmarshal(b, &cj) == nil && cj.Name != "" {
+			note("composer.json")
+			set(cj.Name, cj.Version)
+			if l, ok := cj.License.(string); ok {
+				license = firstNonEmpty(license, l)
+			}
+		}
+	}
+	for _, file := range []string{"Cargo.toml", "pyproject.toml"} {
+		b, err := os.ReadFile(filepath.Join(pkgDir, file))
+		if err != nil {
+			continue
+		}
+		type meta struct {
+			Name    string `toml:"name"`
+			Version string `toml:"version"`
+			License any    `toml:"license"` // Cargo string; PEP 621 string or {text = ...}
+		}
+		var mf struct {
+			Package meta `toml:"package"`
+			Project meta `toml:"project"`
+		}
+		if toml.Unmarshal(b, &mf) != nil {
+			continue
+		}
+		for _, m := range []meta{mf.Package, mf.Project} {
+			if m.Name == "" {
+				continue
+			}
+			note(file)
+			set(m.Name, m.Version)
+			if l, ok := m.License.(string); ok {
+				license = firstNonEmpty(license, l)
+			}
+		}
+	}
+	for _, file := range []string{"VERSION", "VERSION.txt", "version.txt"} {
+		if b, err := os.ReadFile(filepath.Join(pkgDir, file)); err == nil {
+			if v := strings.TrimSpace(string(b)); v != "" && !strings.ContainsAny(v, "\n ") {
+				note(file)
+				set("", v)
+			}
+			break
+		}
+	}
+
+	licenseFiles, _ := filepath.Glob(filepath.Join(pkgDir, "LICEN[CS]E*"))
+	copying, _ := filepath.Glob(filepath.Join(pkgDir, "COPYING*"))
+	licenseFiles = append(licenseFiles, copying...)
+	sort.Strings(licenseFiles)
+	for _, lf := range licenseFiles {
+		note(filepath.Base(lf))
+		if license == "" {
+			license = detectLicense(lf)
+		}
+	}
+
+	if len(evidence) == 0 {
+		return PackageRef{}, false
+	}
+	ref.Source = evidence[0]
+	if license != "" {
+		ref.Licenses = []string{license}
+	}
+	return ref, true
+}
+
+// readReadmeChromium reads the "Field: value" header of a README.chromium
+// file, the metadata Chromium-style third_party directories carry.
+func readReadmeChromium(path string) (map[string]string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	fields := make(map[string]string)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" && len(fields) > 0 {
+			break // free-form description follows
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			fields[k] = strings.TrimSpace(v)
+		}
+	}
+	return fields, len(fields) > 0
+}
+
+// licenseTexts are phrases identifying common license texts, checked in
+// order; the LGPL before the GPL, whose name it contains.
+var licenseTexts = []struct{ phrase, id string }{
+	{"Apache License", "Apache-2.0"},
+	{"Mozilla Public License Version 2.0", "MPL-2.0"},
+	{"GNU LESSER GENERAL PUBLIC LICENSE", "LGPL"},
+	{"GNU AFFERO GENERAL PUBLIC LICENSE", "AGPL-3.0"},
+	{"GNU GENERAL PUBLIC LICENSE", "GPL"},
+	{"Boost Software License", "BSL-1.0"},
+	{"zlib License", "Zlib"},
+	{"This is free and unencumbered software released into the public domain", "Unlicense"},
+	{"Permission is hereby granted, free of charge", "MIT"},
+	{"Neither the name", "BSD-3-Clause"},
+	{"Redistribution and use in source and binary forms", "BSD-2-Clause"},
+	{"Permission to use, copy, modify, and/or distribute this software for any purpose", "ISC"},
+}
+
+// detectLicense names the license a license file contains, by its
+// well-known phrases; "" when none matches.
+func detectLicense(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	text := strings.Join(strings.Fields(string(b)), " ") // line breaks differ between copies
+	for _, l := range licenseTexts {
+		if strings.Contains(text, l.phrase) {
+			if l.id == "GPL" || l.id == "LGPL" {
+				for _, v := range []string{"3", "2.1", "2"} {
+					if strings.Contains(text, "Version "+v+",") {
+						if !strings.Contains(v, ".") {
+							v += ".0"
+						}
+						return l.id + "-" + v
+					}
+				}
+			}
+			return l.id
+		}
+	}
+	return ""
+}
+
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
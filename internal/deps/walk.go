@@ -0,0 +1,153 @@
+package deps
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// projectDirs returns the directories recursive discovery visits, as
+// slash-separated paths relative to root ("." for root itself). Hidden
+// directories, node_modules, vendor, virtualenvs, anything ignored by a
+// .gitignore and directories matching opts.Exclude are skipped along with
+// everything below them; with opts.Include set, only matching directories
+// are returned.
+func projectDirs(root string, opts Options) []string {
+	var dirs []string
+	var rules []ignoreRule
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		if rel != "." {
+			name := d.Name()
+			if strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor" ||
+				fileExists(filepath.Join(p, "pyvenv.cfg")) || ignored(rules, rel) || matchAny(opts.Exclude, rel) {
+				return filepath.SkipDir
+			}
+		}
+		rules = append(rules, readGitignore(p, rel)...)
+		if len(opts.Include) == 0 || matchAny(opts.Include, rel) {
+			dirs = append(dirs, rel)
+		}
+		return nil
+	})
+	return dirs
+}
+
+// ignoreRule is one pattern of a .gitignore file.
+type ignoreRule struct {
+	base     string // directory of the .gitignore, relative to the scanned root
+	pattern  string
+	negate   bool
+	anchored bool // contains a slash, so it matches relative to base only
+}
+
+func readGitignore(dir, rel string) []ignoreRule {
+	b, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	var rules []ignoreRule
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := ignoreRule{base: rel}
+		if strings.HasPrefix(line, "!") {
+			r.negate, line = true, line[1:]
+		}
+		line = strings.TrimSuffix(line, "/") // only directories are matched anyway
+		r.anchored = strings.Contains(line, "/")
+		r.pattern = strings.TrimPrefix(line, "/")
+		if r.pattern != "" {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// ignored reports whether the directory rel is ignored; like git, the last
+// matching rule wins.
+func ignored(rules []ignoreRule, rel string) bool {
+	result := false
+	for _, r := range rules {
+		p := rel
+		if r.base != "." {
+			if !strings.HasPrefix(rel, r.base+"/") {
+				continue
+			}
+			p = rel[len(r.base)+1:]
+		}
+		if !r.anchored {
+			p = path.Base(p)
+		}
+		if matchGlob(r.pattern, p) {
+			result = !r.negate
+		}
+	}
+	return result
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if matchGlob(strings.Trim(p, "/"), rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash-separated path against a glob in which "**"
+// stands for any number of path segments.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, segs []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(pattern[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segs[0]); !ok {
+			return false
+		}
+		pattern, segs = pattern[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
+
+// joinSubproject prefixes the subproject a discoverer reported with the
+// directory it was run in.
+func joinSubproject(rel, sub string) string {
+	if rel == "." {
+		return sub
+	}
+	if sub == "" {
+		return rel
+	}
+	return rel + "/" + sub
+}
+
+// within reports whether rel is one of dirs or below one of them.
+func within(rel string, dirs []string) bool {
+	for _, d := range dirs {
+		if d == "." || rel == d || strings.HasPrefix(rel, d+"/") {
+			return true
+		}
+	}
+	return false
+}
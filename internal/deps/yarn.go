@@ -0,0 +1,225 @@
+package deps
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// yarnEntry is one resolved package of a yarn.lock, shared by every
+// descriptor ("name@range") that resolved to it.
+type yarnEntry struct {
+	Descriptors  []string
+	Version      string
+	Resolution   string
+	Integrity    string
+	Dependencies map[string]string
+	Optional     map[string]bool
+}
+
+func discoverYarn(dir string) []PackageRef {
+	b, err := os.ReadFile(filepath.Join(dir, "yarn.lock"))
+	if err != nil {
+		return nil
+	}
+
+	var entries []yarnEntry
+	berry := bytes.Contains(b, []byte("__metadata:"))
+	if berry {
+		entries = parseYarnBerry(b)
+	} else {
+		entries = parseYarnClassic(b)
+	}
+
+	g := newLockGraph()
+	var prodRoots, devRoots []string
+	addRoots := func(deps map[string]string, pkgJSON npmLockPackage) {
+		for name, rng := range deps {
+			key := yarnDescriptor(name, rng, berry)
+			if _, ok := pkgJSON.DevDependencies[name]; ok {
+				devRoots = append(devRoots, key)
+			} else {
+				prodRoots = append(prodRoots, key)
+			}
+		}
+	}
+
+	for _, e := range entries {
+		name := yarnDescriptorName(e.Descriptors[0])
+		if ws, ok := yarnWorkspacePath(e.Resolution); ok {
+			// workspaces are the project itself; their dependencies are direct
+			addRoots(e.Dependencies, readPackageJSON(filepath.Join(dir, ws)))
+			continue
+		}
+		n := &lockNode{ref: PackageRef{
+			Ecosystem: "npm",
+			Name:      name,
+			Version:   e.Version,
+			Source:    "yarn.lock",
+			Integrity: e.Integrity,
+		}}
+		for dep, rng := range e.Dependencies {
+			n.deps = append(n.deps, lockEdge{key: yarnDescriptor(dep, rng, berry), optional: e.Optional[dep]})
+		}
+		g.add(e.Descriptors, n)
+	}
+
+	if !berry {
+		// classic lockfiles don't record the project's own requirements
+		root := readPackageJSON(dir)
+		addRoots(root.Dependencies, root)
+		addRoots(root.OptionalDependencies, root)
+		addRoots(root.DevDependencies, root)
+	}
+	return g.refs(prodRoots, devRoots)
+}
+
+// parseYarnClassic parses the custom v1 lockfile format:
+//
+//	"@babel/code-frame@^7.0.0", "@babel/code-frame@^7.10.4":
+//	  version "7.12.13"
+//	  integrity sha512-...
+//	  dependencies:
+//	    "@babel/highlight" "^7.12.13"
+func parseYarnClassic(b []byte) []yarnEntry {
+	var entries []yarnEntry
+	var cur *yarnEntry
+	var block string
+
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := sc.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		switch {
+		case indent == 0:
+			entries = append(entries, yarnEntry{
+				Dependencies: map[string]string{},
+				Optional:     map[string]bool{},
+			})
+			cur = &entries[len(entries)-1]
+			for _, d := range strings.Split(strings.TrimSuffix(trimmed, ":"), ",") {
+				cur.Descriptors = append(cur.Descriptors, unquote(strings.TrimSpace(d)))
+			}
+			block = ""
+		case cur == nil:
+			continue
+		case indent == 2:
+			key, val := splitYarnField(trimmed)
+			block = ""
+			switch key {
+			case "version":
+				cur.Version = val
+			case "resolved":
+				cur.Resolution = val
+			case "integrity":
+				cur.Integrity = val
+			case "dependencies:", "optionalDependencies:":
+				block = key
+			}
+		case indent >= 4 && block != "":
+			name, rng := splitYarnField(trimmed)
+			cur.Dependencies[name] = rng
+			if block == "optionalDependencies:" {
+				cur.Optional[name] = true
+			}
+		}
+	}
+	return entries
+}
+
+func parseYarnBerry(b []byte) []yarnEntry {
+	type berryEntry struct {
+		Version          string            `yaml:"version"`
+		Resolution       string            `yaml:"resolution"`
+		Checksum         string            `yaml:"checksum"`
+		Dependencies     map[string]string `yaml:"dependencies"`
+		DependenciesMeta map[string]struct {
+			Optional bool `yaml:"optional"`
+		} `yaml:"dependenciesMeta"`
+	}
+	var doc map[string]berryEntry
+	if yaml.Unmarshal(b, &doc) != nil {
+		return nil
+	}
+
+	var entries []yarnEntry
+	for key, be := range doc {
+		if key == "__metadata" {
+			continue
+		}
+		e := yarnEntry{
+			Version:      be.Version,
+			Resolution:   be.Resolution,
+			Integrity:    be.Checksum,
+			Dependencies: be.Dependencies,
+			Optional:     map[string]bool{},
+		}
+		// the resolution is always quoted, so it keeps versions YAML would read as numbers
+		if idx := strings.LastIndex(be.Resolution, "@npm:"); idx > 0 {
+			e.Version = be.Resolution[idx+len("@npm:"):]
+		}
+		for name, meta := range be.DependenciesMeta {
+			e.Optional[name] = meta.Optional
+		}
+		for _, d := range strings.Split(key, ",") {
+			e.Descriptors = append(e.Descriptors, strings.TrimSpace(d))
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// yarnDescriptor builds the lockfile key a dependency range resolves through.
+// Berry qualifies plain semver ranges with the npm: protocol.
+func yarnDescriptor(name, rng string, berry bool) string {
+	if berry && !strings.Contains(rng, ":") {
+		return name + "@npm:" + rng
+	}
+	return name + "@" + rng
+}
+
+// yarnDescriptorName strips the range from "name@range", keeping scoped names intact.
+func yarnDescriptorName(desc string) string {
+	if len(desc) < 2 {
+		return desc
+	}
+	if idx := strings.Index(desc[1:], "@"); idx >= 0 {
+		return desc[:idx+1]
+	}
+	return desc
+}
+
+func yarnWorkspacePath(resolution string) (string, bool) {
+	idx := strings.Index(resolution, "@workspace:")
+	if idx < 0 {
+		return "", false
+	}
+	return resolution[idx+len("@workspace:"):], true
+}
+
+// splitYarnField splits `key "value"` or `"@scope/key" "value"` lines.
+func splitYarnField(s string) (string, string) {
+	if strings.HasPrefix(s, `"`) {
+		if end := strings.Index(s[1:], `"`); end >= 0 {
+			return s[1 : end+1], unquote(strings.TrimSpace(s[end+2:]))
+		}
+	}
+	key, val, found := strings.Cut(s, " ")
+	if !found {
+		return s, ""
+	}
+	return key, unquote(strings.TrimSpace(val))
+}
+
+func unquote(s string) string {
+	return strings.Trim(s, `"`)
+}
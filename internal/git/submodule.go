@@ -0,0 +1,141 @@
+package git
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Submodule is a submodule declared in .gitmodules.
+type Submodule struct {
+	Name   string
+	Path   string // relative to the repository root
+	URL    string // relative URLs are resolved against the origin remote
+	Branch string // branch "git submodule update --remote" follows, if set
+	Commit string // commit the superproject pins, "" if unknown
+	Remote Remote
+}
+
+// NewRemote describes a remote from its URL.
+func NewRemote(name, rawURL string) Remote {
+	r := Remote{Name: name, URL: rawURL}
+	r.Kind, r.Host, r.Path = parseRemoteURL(rawURL)
+	return r
+}
+
+// GetSubmodules reads dir/.gitmodules and looks up the commit HEAD pins
+// each submodule to. Submodules need not be checked out.
+func GetSubmodules(dir string) []Submodule {
+	subs := readGitmodules(filepath.Join(dir, ".gitmodules"))
+	if len(subs) == 0 {
+		return nil
+	}
+
+	var origin string
+	for _, r := range GetRemotes(dir) {
+		if r.Name == "origin" {
+			origin = r.URL
+		}
+	}
+	paths := []string{"ls-tree", "HEAD", "--"}
+	for i := range subs {
+		if strings.HasPrefix(subs[i].URL, "./") || strings.HasPrefix(subs[i].URL, "../") {
+			subs[i].URL = resolveRelativeURL(origin, subs[i].URL)
+		}
+		subs[i].Remote = NewRemote(subs[i].Name, subs[i].URL)
+		paths = append(paths, subs[i].Path)
+	}
+
+	// "160000 commit <sha>\t<path>" for every gitlink
+	cmd := exec.Command("git", paths...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return subs
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		meta, p, ok := strings.Cut(line, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) < 3 || fields[1] != "commit" {
+			continue
+		}
+		for i := range subs {
+			if subs[i].Path == p {
+				subs[i].Commit = fields[2]
+			}
+		}
+	}
+	return subs
+}
+
+// readGitmodules parses the [submodule "name"] sections of a .gitmodules
+// file.
+func readGitmodules(file string) []Submodule {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var subs []Submodule
+	var cur *Submodule
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			cur = nil
+			if name, ok := strings.CutPrefix(strings.TrimSuffix(line, "]"), "[submodule"); ok {
+				subs = append(subs, Submodule{Name: strings.Trim(strings.TrimSpace(name), `"`)})
+				cur = &subs[len(subs)-1]
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || cur == nil {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "path":
+			cur.Path = value
+		case "url":
+			cur.URL = value
+		case "branch":
+			cur.Branch = value
+		}
+	}
+
+	out := subs[:0]
+	for _, s := range subs {
+		if s.Path != "" && s.URL != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// resolveRelativeURL resolves a submodule URL such as "../lib.git" against
+// the superproject's remote URL, the way git does.
+func resolveRelativeURL(base, rel string) string {
+	if base == "" {
+		return rel
+	}
+	base = strings.TrimSuffix(base, "/")
+	prefix := ""
+	if i := strings.Index(base, "://"); i >= 0 {
+		// scheme://host/path: keep the host out of path.Join
+		if j := strings.Index(base[i+3:], "/"); j >= 0 {
+			prefix, base = base[:i+3+j], base[i+3+j:]
+		}
+	} else if i := strings.Index(base, ":"); i >= 0 {
+		// scp-like git@host:owner/repo
+		prefix, base = base[:i+1], base[i+1:]
+	}
+	return prefix + path.Join(base, rel)
+}
@@ -1,11 +1,8 @@
 package graph
 
 import (
-	"bufio"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 
 	"sbom-report/internal/deps"
@@ -17,7 +14,7 @@ type Node struct {
 	ID           string
 	Label        string
 	FullName     string
-	Type         string // "project", "go", "npm", "python", "maven", "repo"
+	Type         string // "project", "go", "npm", "python", "maven", "cargo", "nuget", "composer", "gem", "repo"
 	X, Y         float64
 	Level        int // Depth in dependency tree
 	Color        string
@@ -39,7 +36,7 @@ type Graph struct {
 }
 
 // GenerateDependencyGraph creates an SVG visualization of dependencies
-func GenerateDependencyGraph(outputPath string, projectName string, goMods []deps.GoModule, npmPkgs, pythonPkgs, mavenDeps []deps.PackageRef, repos []repo.Assessment) error {
+func GenerateDependencyGraph(outputPath string, projectName string, packages deps.Collection, repos []repo.Assessment) error {
 	g := &Graph{
 		Nodes:        []Node{},
 		Edges:        []Edge{},
@@ -58,156 +55,62 @@ func GenerateDependencyGraph(outputPath string, projectName string, goMods []dep
 		Level:    0,
 	})
 
-	// Get base directory for go mod graph
-	baseDir := filepath.Dir(outputPath)
-	if wd, err := os.Getwd(); err == nil {
-		baseDir = wd
+	// Use the parent links the discoverers record (lockfiles, POMs, go mod graph)
+	for _, e := range packages {
+		addPackageTree(g, rootID, e.Ecosystem.ID, e.Packages, repos)
 	}
 
-	// Parse Go module dependencies with transitive relationships
-	if len(goMods) > 0 {
-		parseGoModGraph(g, rootID, baseDir, repos)
-	}
+	// Calculate levels for all nodes based on dependency depth
+	g.calculateLevels(rootID)
 
-	// For NPM, Python, Maven - add as direct dependencies for now
-	// (can be enhanced later to parse their dependency trees)
-	for _, pkg := range npmPkgs {
-		nodeID := sanitizeID("npm-" + pkg.Name)
-		if !g.hasNode(nodeID) {
-			isVuln := hasVulnerability(pkg.Name, repos)
-			g.addNode(Node{
-				ID:           nodeID,
-				Label:        truncate(pkg.Name, 40),
-				FullName:     pkg.Name,
-				Type:         "npm",
-				Color:        getColorByType("npm", isVuln),
-				IsVulnerable: isVuln,
-				Level:        1,
-			})
-			g.addEdge(rootID, nodeID)
-		}
-	}
+	// Layout the graph hierarchically
+	layoutHierarchical(g)
 
-	for _, pkg := range pythonPkgs {
-		nodeID := sanitizeID("python-" + pkg.Name)
-		if !g.hasNode(nodeID) {
-			isVuln := hasVulnerability(pkg.Name, repos)
-			g.addNode(Node{
-				ID:           nodeID,
-				Label:        truncate(pkg.Name, 40),
-				FullName:     pkg.Name,
-				Type:         "python",
-				Color:        getColorByType("python", isVuln),
-				IsVulnerable: isVuln,
-				Level:        1,
-			})
-			g.addEdge(rootID, nodeID)
-		}
-	}
+	// Generate SVG
+	return generateSVG(g, outputPath)
+}
 
-	for _, pkg := range mavenDeps {
-		nodeID := sanitizeID("maven-" + pkg.Name)
+// addPackageTree adds packages of one ecosystem, linking each to the packages
+// that depend on it. Packages without known parents hang off the root.
+func addPackageTree(g *Graph, rootID, nodeType string, pkgs []deps.PackageRef, repos []repo.Assessment) {
+	for _, pkg := range pkgs {
+		nodeID := sanitizeID(nodeType + "-" + pkg.Name)
 		if !g.hasNode(nodeID) {
 			isVuln := hasVulnerability(pkg.Name, repos)
 			g.addNode(Node{
 				ID:           nodeID,
 				Label:        truncate(pkg.Name, 40),
 				FullName:     pkg.Name,
-				Type:         "maven",
-				Color:        getColorByType("maven", isVuln),
+				Type:         nodeType,
+				Color:        getColorByType(nodeType, isVuln),
 				IsVulnerable: isVuln,
 				Level:        1,
 			})
-			g.addEdge(rootID, nodeID)
 		}
 	}
 
-	// Calculate levels for all nodes based on dependency depth
-	g.calculateLevels(rootID)
-
-	// Layout the graph hierarchically
-	layoutHierarchical(g)
-
-	// Generate SVG
-	return generateSVG(g, outputPath)
-}
-
-// parseGoModGraph parses the output of `go mod graph` to get transitive dependencies
-func parseGoModGraph(g *Graph, rootID, baseDir string, repos []repo.Assessment) {
-	cmd := exec.Command("go", "mod", "graph")
-	cmd.Dir = baseDir
-	output, err := cmd.Output()
-	if err != nil {
-		// Fallback: can't get graph, skip Go dependencies
-		return
-	}
-
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
+	linked := make(map[string]bool)
+	link := func(from, to string) {
+		if from == to || linked[from+"->"+to] {
+			return
 		}
-
-		parts := strings.Fields(line)
-		if len(parts) != 2 {
-			continue
-		}
-
-		fromPkg := parts[0]
-		toPkg := parts[1]
-
-		// Determine if this is a direct dependency from the project
-		var fromID string
-		if strings.HasPrefix(fromPkg, "sbom-report") || !strings.Contains(fromPkg, "@") {
-			fromID = rootID
-		} else {
-			fromID = sanitizeID("go-" + fromPkg)
-		}
-
-		toID := sanitizeID("go-" + toPkg)
-
-		// Extract package name from versioned string (e.g., "github.com/foo/bar@v1.2.3" -> "github.com/foo/bar")
-		toName := extractPackageName(toPkg)
-		isVuln := hasVulnerability(toName, repos)
-
-		// Add the "to" node if it doesn't exist
-		if !g.hasNode(toID) {
-			g.addNode(Node{
-				ID:           toID,
-				Label:        truncate(toName, 50),
-				FullName:     toPkg,
-				Type:         "go",
-				Color:        getColorByType("go", isVuln),
-				IsVulnerable: isVuln,
-			})
+		linked[from+"->"+to] = true
+		g.addEdge(from, to)
+	}
+	for _, pkg := range pkgs {
+		nodeID := sanitizeID(nodeType + "-" + pkg.Name)
+		if pkg.Direct || len(pkg.Parents) == 0 {
+			link(rootID, nodeID)
 		}
-
-		// Add the "from" node if it's not the root and doesn't exist
-		if fromID != rootID && !g.hasNode(fromID) {
-			fromName := extractPackageName(fromPkg)
-			fromVuln := hasVulnerability(fromName, repos)
-			g.addNode(Node{
-				ID:           fromID,
-				Label:        truncate(fromName, 50),
-				FullName:     fromPkg,
-				Type:         "go",
-				Color:        getColorByType("go", fromVuln),
-				IsVulnerable: fromVuln,
-			})
+		for _, parent := range pkg.Parents {
+			parentID := sanitizeID(nodeType + "-" + parent)
+			if g.hasNode(parentID) {
+				link(parentID, nodeID)
+			} else {
+				link(rootID, nodeID)
+			}
 		}
-
-		// Add edge
-		g.addEdge(fromID, toID)
-	}
-}
-
-// extractPackageName extracts the package path from a versioned string
-func extractPackageName(pkg string) string {
-	if idx := strings.Index(pkg, "@"); idx != -1 {
-		return pkg[:idx]
 	}
-	return pkg
 }
 
 // Graph helper methods
@@ -222,6 +125,11 @@ func (g *Graph) hasNode(id string) bool {
 }
 
 func (g *Graph) addEdge(from, to string) {
+	for _, existing := range g.adjacencyMap[from] {
+		if existing == to {
+			return
+		}
+	}
 	g.Edges = append(g.Edges, Edge{From: from, To: to})
 	g.adjacencyMap[from] = append(g.adjacencyMap[from], to)
 }
@@ -538,17 +446,17 @@ func generateSVG(g *Graph, outputPath string) error {
 	// Add legend
 	legendX := 20.0
 	legendY := 85.0
-	legendItems := []struct {
+	type legendItem struct {
 		label string
 		color string
-	}{
+	}
+	legendItems := []legendItem{
 		{"Project Root", "#7BEFB2"},
-		{"Go Module", "#00ADD8"},
-		{"NPM Package", "#CB3837"},
-		{"Python Package", "#3776AB"},
-		{"Maven Dependency", "#B07219"},
-		{"Has Vulnerabilities", "#f85149"},
 	}
+	for _, e := range deps.Ecosystems() {
+		legendItems = append(legendItems, legendItem{e.Label, e.Color})
+	}
+	legendItems = append(legendItems, legendItem{"Has Vulnerabilities", "#f85149"})
 
 	svg.WriteString(`<g id="legend">`)
 	svg.WriteString("\n")
@@ -557,7 +465,7 @@ func generateSVG(g *Graph, outputPath string) error {
 		svg.WriteString(fmt.Sprintf(`<circle cx="%.1f" cy="%.1f" r="6" fill="%s"/>`,
 			legendX, y, item.color))
 		svg.WriteString(fmt.Sprintf(`<text x="%.1f" y="%.1f" class="legend-text">%s</text>`,
-			legendX+18, y+5, item.label))
+			legendX+18, y+5, escapeXML(item.label)))
 		svg.WriteString("\n")
 	}
 	svg.WriteString(`</g>`)
@@ -612,18 +520,10 @@ func getColorByType(nodeType string, isVulnerable bool) string {
 	if isVulnerable {
 		return "#f85149" // Red for vulnerable
 	}
-	switch nodeType {
-	case "go":
-		return "#00ADD8"
-	case "npm":
-		return "#CB3837"
-	case "python":
-		return "#3776AB"
-	case "maven":
-		return "#B07219"
-	default:
-		return "#8b949e"
+	if e, ok := deps.LookupEcosystem(nodeType); ok && e.Color != "" {
+		return e.Color
 	}
+	return "#8b949e"
 }
 
 func hasVulnerability(pkgName string, repos []repo.Assessment) bool {
@@ -0,0 +1,469 @@
+// Package image unpacks container images saved as docker-archive
+// ("docker save") or OCI image-layout tarballs into a plain directory, so
+// they can be scanned like a source tree without a daemon or registry.
+package image
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Image describes an unpacked image.
+type Image struct {
+	Name         string // first repo tag or OCI ref name, if any
+	ID           string // config digest
+	OS           string
+	Architecture string
+	Created      string
+	BaseName     string // base image, from the OCI base-image annotations or labels
+	BaseDigest   string
+	Layers       []Layer
+	RootFS       string // directory the layers were applied to
+
+	files map[string]int // slash path below RootFS -> index of the layer that last wrote it
+}
+
+// Layer is one filesystem layer, in application order.
+type Layer struct {
+	Digest    string // blob digest as stored in the archive
+	DiffID    string // digest of the uncompressed layer, from the image config
+	CreatedBy string // the build step that produced it
+	Files     int    // entries the layer adds or changes
+	Removed   int    // whiteouts it applies
+}
+
+// LayerOf returns the layer that last wrote path (slash-separated, relative
+// to RootFS), or of the closest parent directory it knows.
+func (img *Image) LayerOf(p string) (Layer, bool) {
+	for p = path.Clean(p); p != "." && p != "/"; p = path.Dir(p) {
+		if i, ok := img.files[p]; ok {
+			return img.Layers[i], true
+		}
+	}
+	return Layer{}, false
+}
+
+// imageConfig is the part of the image configuration JSON used here.
+type imageConfig struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Created      string `json:"created"`
+	Config       struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+	RootFS struct {
+		DiffIDs []string `json:"diff_ids"`
+	} `json:"rootfs"`
+	History []struct {
+		CreatedBy  string `json:"created_by"`
+		EmptyLayer bool   `json:"empty_layer"`
+	} `json:"history"`
+}
+
+type dockerManifest struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+	Platform    *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform"`
+}
+
+type ociIndex struct {
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+type ociManifest struct {
+	Config      ociDescriptor     `json:"config"`
+	Layers      []ociDescriptor   `json:"layers"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+const (
+	annotationRefName    = "org.opencontainers.image.ref.name"
+	annotationBaseName   = "org.opencontainers.image.base.name"
+	annotationBaseDigest = "org.opencontainers.image.base.digest"
+)
+
+// Load unpacks the image in src, a docker-archive or OCI image-layout
+// tarball (optionally gzip-compressed) or an already extracted OCI layout
+// directory, applying its layers in order to dest/rootfs.
+func Load(src, dest string) (*Image, error) {
+	layout := src
+	if st, err := os.Stat(src); err != nil {
+		return nil, err
+	} else if !st.IsDir() {
+		layout = filepath.Join(dest, "archive")
+		if err := extractArchive(src, layout); err != nil {
+			return nil, fmt.Errorf("unpack %s: %w", src, err)
+		}
+	}
+
+	img := &Image{RootFS: filepath.Join(dest, "rootfs"), files: make(map[string]int)}
+	var configPath string
+	var layerPaths []string
+	annotations := map[string]string{}
+	if b, err := os.ReadFile(filepath.Join(layout, "manifest.json")); err == nil {
+		var manifests []dockerManifest
+		if err := json.Unmarshal(b, &manifests); err != nil || len(manifests) == 0 {
+			return nil, fmt.Errorf("invalid manifest.json")
+		}
+		m := manifests[0]
+		if len(m.RepoTags) > 0 {
+			img.Name = m.RepoTags[0]
+		}
+		configPath = filepath.Join(layout, filepath.FromSlash(m.Config))
+		for _, l := range m.Layers {
+			layerPaths = append(layerPaths, filepath.Join(layout, filepath.FromSlash(l)))
+			img.Layers = append(img.Layers, Layer{Digest: digestFromPath(l)})
+		}
+	} else {
+		m, desc, err := readOCIManifest(layout)
+		if err != nil {
+			return nil, err
+		}
+		img.Name = desc.Annotations[annotationRefName]
+		annotations = m.Annotations
+		configPath = blobPath(layout, m.Config.Digest)
+		for _, l := range m.Layers {
+			layerPaths = append(layerPaths, blobPath(layout, l.Digest))
+			img.Layers = append(img.Layers, Layer{Digest: l.Digest})
+		}
+	}
+
+	var cfg imageConfig
+	b, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("read image config: %w", err)
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("parse image config: %w", err)
+	}
+	img.ID = fmt.Sprintf("sha256:%x", sha256.Sum256(b)) // the image ID is the config digest
+	img.OS, img.Architecture, img.Created = cfg.OS, cfg.Architecture, cfg.Created
+	base := annotations
+	if base[annotationBaseName] == "" && base[annotationBaseDigest] == "" {
+		base = cfg.Config.Labels
+	}
+	img.BaseName, img.BaseDigest = base[annotationBaseName], base[annotationBaseDigest]
+
+	var steps []string // history entries that produced a layer
+	for _, h := range cfg.History {
+		if !h.EmptyLayer {
+			steps = append(steps, h.CreatedBy)
+		}
+	}
+	for i := range img.Layers {
+		if i < len(cfg.RootFS.DiffIDs) {
+			img.Layers[i].DiffID = cfg.RootFS.DiffIDs[i]
+		}
+		if len(steps) == len(img.Layers) {
+			img.Layers[i].CreatedBy = steps[i]
+		}
+	}
+
+	if err := os.MkdirAll(img.RootFS, 0o755); err != nil {
+		return nil, err
+	}
+	for i, p := range layerPaths {
+		if err := img.applyLayer(i, p); err != nil {
+			return nil, fmt.Errorf("layer %d (%s): %w", i, img.Layers[i].Digest, err)
+		}
+	}
+	return img, nil
+}
+
+// readOCIManifest follows index.json to the image manifest, preferring a
+// linux/amd64 entry of a multi-platform index.
+func readOCIManifest(layout string) (*ociManifest, ociDescriptor, error) {
+	b, err := os.ReadFile(filepath.Join(layout, "index.json"))
+	if err != nil {
+		return nil, ociDescriptor{}, fmt.Errorf("neither manifest.json nor index.json found: not a docker-archive or OCI layout")
+	}
+	var index ociIndex
+	if err := json.Unmarshal(b, &index); err != nil {
+		return nil, ociDescriptor{}, fmt.Errorf("parse index.json: %w", err)
+	}
+	for depth := 0; depth < 4 && len(index.Manifests) > 0; depth++ {
+		desc := index.Manifests[0]
+		for _, d := range index.Manifests {
+			if d.Platform != nil && d.Platform.OS == "linux" && d.Platform.Architecture == "amd64" {
+				desc = d
+				break
+			}
+		}
+		b, err := os.ReadFile(blobPath(layout, desc.Digest))
+		if err != nil {
+			return nil, desc, fmt.Errorf("read manifest %s: %w", desc.Digest, err)
+		}
+		if strings.Contains(desc.MediaType, "index") || strings.Contains(desc.MediaType, "manifest.list") {
+			var nested ociIndex
+			if err := json.Unmarshal(b, &nested); err != nil {
+				return nil, desc, err
+			}
+			if desc.Annotations[annotationRefName] != "" {
+				for i := range nested.Manifests {
+					if nested.Manifests[i].Annotations == nil {
+						nested.Manifests[i].Annotations = map[string]string{}
+					}
+					nested.Manifests[i].Annotations[annotationRefName] = desc.Annotations[annotationRefName]
+				}
+			}
+			index = nested
+			continue
+		}
+		var m ociManifest
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, desc, fmt.Errorf("parse manifest %s: %w", desc.Digest, err)
+		}
+		return &m, desc, nil
+	}
+	return nil, ociDescriptor{}, fmt.Errorf("no image manifest in index.json")
+}
+
+func blobPath(layout, digest string) string {
+	alg, hex, _ := strings.Cut(digest, ":")
+	return filepath.Join(layout, "blobs", alg, hex)
+}
+
+// digestFromPath turns a docker-archive layer path ("blobs/sha256/<hex>",
+// "<id>/layer.tar") into a digest or layer ID.
+func digestFromPath(p string) string {
+	p = filepath.ToSlash(p)
+	base := path.Base(p)
+	if strings.HasSuffix(path.Dir(p), "blobs/sha256") {
+		return "sha256:" + base
+	}
+	if base == "layer.tar" {
+		return path.Base(path.Dir(p)) // legacy "<id>/layer.tar"
+	}
+	return base
+}
+
+// applyLayer extracts one layer tarball over RootFS. Whiteout entries remove
+// what lower layers put there: ".wh.<name>" deletes name, ".wh..wh..opq"
+// empties the directory except for what this layer itself adds.
+func (img *Image) applyLayer(index int, p string) error {
+	r, closer, err := openTar(p)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	layer := &img.Layers[index]
+	written := make(map[string]bool) // paths, and their parents, this layer wrote
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := cleanName(hdr.Name)
+		if name == "" {
+			continue
+		}
+		dir, base := path.Split(name)
+		dir = strings.TrimSuffix(dir, "/")
+
+		if base == ".wh..wh..opq" {
+			target := img.path(dir)
+			entries, _ := os.ReadDir(target)
+			for _, e := range entries {
+				child := path.Join(dir, e.Name())
+				if !written[child] {
+					img.remove(child)
+					layer.Removed++
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(base, ".wh.") {
+			img.remove(path.Join(dir, strings.TrimPrefix(base, ".wh.")))
+			layer.Removed++
+			continue
+		}
+
+		if !img.safeParent(name) {
+			continue // would write through a symlink
+		}
+		target := img.path(name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if st, err := os.Lstat(target); err == nil && !st.IsDir() {
+				os.RemoveAll(target)
+			}
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			os.RemoveAll(target)
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode)&0o755|0o600)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			os.RemoveAll(target)
+			// resolve the link as the image's root would, and point it
+			// there relatively so it cannot leave the unpacked tree
+			resolved := hdr.Linkname
+			if !path.IsAbs(resolved) {
+				resolved = path.Join(dir, resolved)
+			}
+			link, err := filepath.Rel(filepath.Dir(target), filepath.Join(img.RootFS, filepath.FromSlash(cleanName(resolved))))
+			if err != nil {
+				continue
+			}
+			if err := os.Symlink(link, target); err != nil {
+				continue
+			}
+		case tar.TypeLink:
+			src := cleanName(hdr.Linkname)
+			if src == "" || !img.safeParent(src) {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			os.RemoveAll(target)
+			if err := os.Link(img.path(src), target); err != nil {
+				continue
+			}
+		default:
+			continue // devices and fifos carry no packages
+		}
+		layer.Files++
+		img.files[name] = index
+		for d := name; d != "."; d = path.Dir(d) {
+			written[d] = true
+		}
+	}
+}
+
+// openTar opens a layer, transparently decompressing gzip.
+func openTar(p string) (io.Reader, func(), error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(4)
+	switch {
+	case len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return gz, func() { gz.Close(); f.Close() }, nil
+	case len(magic) == 4 && magic[0] == 0x28 && magic[1] == 0xb5 && magic[2] == 0x2f && magic[3] == 0xfd:
+		f.Close()
+		return nil, nil, fmt.Errorf("zstd-compressed layers are not supported")
+	}
+	return br, func() { f.Close() }, nil
+}
+
+// extractArchive unpacks the outer image tarball; only regular files and
+// directories are expected there.
+func extractArchive(src, dest string) error {
+	r, closer, err := openTar(src)
+	if err != nil {
+		return err
+	}
+	defer closer()
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := cleanName(hdr.Name)
+		if name == "" || hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		f, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// cleanName makes a tar entry name relative and free of "..".
+func cleanName(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "." {
+		return ""
+	}
+	return name
+}
+
+func (img *Image) path(name string) string {
+	return filepath.Join(img.RootFS, filepath.FromSlash(name))
+}
+
+// safeParent reports whether none of the existing parent directories of
+// name is a symlink, so writing it stays inside RootFS.
+func (img *Image) safeParent(name string) bool {
+	for d := path.Dir(name); d != "."; d = path.Dir(d) {
+		if st, err := os.Lstat(img.path(d)); err == nil && st.Mode()&os.ModeSymlink != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// remove deletes name and forgets the provenance of everything below it.
+func (img *Image) remove(name string) {
+	if !img.safeParent(name) {
+		return
+	}
+	os.RemoveAll(img.path(name))
+	delete(img.files, name)
+	prefix := name + "/"
+	for p := range img.files {
+		if strings.HasPrefix(p, prefix) {
+			delete(img.files, p)
+		}
+	}
+}
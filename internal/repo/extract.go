@@ -15,13 +15,13 @@ import (
 )
 
 // ExtractReposFromGoModules extracts GitHub repository URLs from Go modules
-func ExtractReposFromGoModules(modules []deps.GoModule) []git.Remote {
+func ExtractReposFromGoModules(_ *config.Config, modules []deps.PackageRef) []git.Remote {
 	var repos []git.Remote
 	seen := make(map[string]bool)
 
 	for _, mod := range modules {
-		if strings.HasPrefix(mod.Path, "github.com/") {
-			parts := strings.Split(strings.TrimPrefix(mod.Path, "github.com/"), "/")
+		if strings.HasPrefix(mod.Name, "github.com/") {
+			parts := strings.Split(strings.TrimPrefix(mod.Name, "github.com/"), "/")
 			if len(parts) >= 2 {
 				owner := parts[0]
 				repo := parts[1]
@@ -30,7 +30,7 @@ func ExtractReposFromGoModules(modules []deps.GoModule) []git.Remote {
 				if !seen[key] {
 					seen[key] = true
 					repos = append(repos, git.Remote{
-						Name: mod.Path,
+						Name: mod.Name,
 						URL:  "https://github.com/" + owner + "/" + repo,
 						Kind: "https",
 						Host: "github.com",
@@ -44,6 +44,70 @@ func ExtractReposFromGoModules(modules []deps.GoModule) []git.Remote {
 	return repos
 }
 
+// packageRepoResolvers look up the source repositories of packages, keyed by
+// ecosystem ID. Ecosystems without a registry lookup are not listed.
+var packageRepoResolvers = map[string]func(*config.Config, []deps.PackageRef) []git.Remote{
+	"go":     ExtractReposFromGoModules,
+	"npm":    ExtractReposFromNpmPackages,
+	"python": ExtractReposFromPythonPackages,
+	"cargo":  ExtractReposFromCargoCrates,
+
+	"github-actions": ExtractReposFromGitHubActions,
+	"git-submodule":  ExtractReposFromSubmodules,
+}
+
+// ExtractReposFromSubmodules returns the remotes git submodules are cloned
+// from.
+func ExtractReposFromSubmodules(_ *config.Config, refs []deps.PackageRef) []git.Remote {
+	var repos []git.Remote
+	seen := make(map[string]bool)
+
+	for _, ref := range refs {
+		if ref.Repository == "" || seen[ref.Repository] {
+			continue
+		}
+		seen[ref.Repository] = true
+		repos = append(repos, git.NewRemote(ref.Name, ref.Repository))
+	}
+
+	return repos
+}
+
+// ExtractReposFromGitHubActions returns the repositories that actions and
+// reusable workflows are taken from. The repository is part of the
+// reference, so nothing is looked up; docker image references are skipped.
+func ExtractReposFromGitHubActions(_ *config.Config, refs []deps.PackageRef) []git.Remote {
+	var repos []git.Remote
+	seen := make(map[string]bool)
+
+	for _, ref := range refs {
+		path := deps.GitHubActionRepo(ref)
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		repos = append(repos, git.Remote{
+			Name: ref.Name,
+			URL:  "https://github.com/" + path,
+			Kind: "https",
+			Host: "github.com",
+			Path: path,
+		})
+	}
+
+	return repos
+}
+
+// ExtractReposFromPackages resolves packages of the given ecosystem to GitHub
+// repositories. ok is false when the ecosystem has no registry lookup.
+func ExtractReposFromPackages(cfg *config.Config, ecosystem string, packages []deps.PackageRef) (repos []git.Remote, ok bool) {
+	resolve, ok := packageRepoResolvers[ecosystem]
+	if !ok {
+		return nil, false
+	}
+	return resolve(cfg, packages), true
+}
+
 type pypiInfo struct {
 	Info struct {
 		ProjectURLs map[string]string `json:"project_urls"`
@@ -64,7 +128,8 @@ func ExtractReposFromPythonPackages(cfg *config.Config, packages []deps.PackageR
 	for _, pkg := range packages {
 		progress.Increment()
 		
-		if pkg.Name == "" || pkg.Name == "(see file)" {
+		// local paths, VCS checkouts and direct URLs are not on PyPI
+		if pkg.Name == "" || pkg.Kind != "" {
 			continue
 		}
 
@@ -156,6 +221,62 @@ func ExtractReposFromNpmPackages(cfg *config.Config, packages []deps.PackageRef)
 	return repos
 }
 
+type cratesIOInfo struct {
+	Crate struct {
+		Repository string `json:"repository"`
+		Homepage   string `json:"homepage"`
+	} `json:"crate"`
+}
+
+// ExtractReposFromCargoCrates queries crates.io and extracts GitHub repository URLs
+func ExtractReposFromCargoCrates(cfg *config.Config, crates []deps.PackageRef) []git.Remote {
+	var repos []git.Remote
+	seen := make(map[string]bool)
+	queried := make(map[string]bool)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.RequestTimeout*5)
+	defer cancel()
+
+	progress := NewProgressBar(len(crates), "Resolving Cargo crates to GitHub")
+
+	for _, pkg := range crates {
+		progress.Increment()
+
+		// git and path dependencies are not published on crates.io; workspace
+		// members often share crates, so look each one up once
+		if pkg.Name == "" || pkg.Kind != "" || queried[pkg.Name] {
+			continue
+		}
+		queried[pkg.Name] = true
+
+		cratesURL := fmt.Sprintf("https://crates.io/api/v1/crates/%s", url.PathEscape(pkg.Name))
+		var info cratesIOInfo
+		if err := httpGetJSON(ctx, cfg, cratesURL, &info); err != nil {
+			continue
+		}
+
+		var repoURL string
+		if strings.Contains(info.Crate.Repository, "github.com") {
+			repoURL = info.Crate.Repository
+		} else if strings.Contains(info.Crate.Homepage, "github.com") {
+			repoURL = info.Crate.Homepage
+		}
+
+		if repoURL != "" {
+			if remote := parseGitHubURL(repoURL); remote != nil {
+				key := remote.Path
+				if !seen[key] {
+					seen[key] = true
+					remote.Name = pkg.Name
+					repos = append(repos, *remote)
+				}
+			}
+		}
+	}
+
+	return repos
+}
+
 func parseGitHubURL(rawURL string) *git.Remote {
 	rawURL = strings.TrimPrefix(rawURL, "git+")
 	rawURL = strings.TrimSuffix(rawURL, ".git")
@@ -3,7 +3,10 @@ package report
 import (
 	"html/template"
 	"os"
+	"strings"
 	"time"
+
+	"sbom-report/internal/deps"
 )
 
 func RenderHTML(outPath string, rep *Report) error {
@@ -14,6 +17,53 @@ func RenderHTML(outPath string, rep *Report) error {
 			}
 			return t.Format(time.RFC3339)
 		},
+		"pkgDetails": func(p deps.PackageRef) string {
+			// sort of reference, then scope, groups and markers as the
+			// ecosystem names them, then flags
+			var parts []string
+			if p.RefType != "" {
+				parts = append(parts, p.RefType)
+			}
+			if p.NativeScope != "" {
+				parts = append(parts, p.NativeScope)
+			}
+			if len(p.Groups) > 0 {
+				parts = append(parts, strings.Join(p.Groups, ", "))
+			}
+			if p.Markers != "" {
+				parts = append(parts, p.Markers)
+			}
+			var flags []string
+			for _, f := range []struct {
+				set  bool
+				name string
+			}{{p.Kind != "", p.Kind}, {p.Dev, "dev"}, {p.Optional, "optional"}, {p.Peer, "peer"}} {
+				if f.set {
+					flags = append(flags, f.name)
+				}
+			}
+			if len(flags) > 0 {
+				parts = append(parts, "("+strings.Join(flags, ", ")+")")
+			}
+			if p.OS != nil {
+				if p.OS.Arch != "" {
+					parts = append(parts, p.OS.Arch)
+				}
+				if p.OS.SourceName != "" && (p.OS.SourceName != p.Name || p.OS.SourceVersion != p.Version) {
+					parts = append(parts, "source: "+strings.TrimSpace(p.OS.SourceName+" "+p.OS.SourceVersion))
+				}
+			}
+			if p.Repository != "" {
+				parts = append(parts, "from "+p.Repository)
+			}
+			if p.Branch != "" {
+				parts = append(parts, "tracks "+p.Branch)
+			}
+			if len(p.Licenses) > 0 {
+				parts = append(parts, "license: "+strings.Join(p.Licenses, ", "))
+			}
+			return strings.Join(parts, " ")
+		},
 		"cvssColor": func(score float64) string {
 			if score >= 9.0 {
 				return "#d32f2f" // Critical - red
@@ -209,11 +259,12 @@ const htmlTemplate = `<!doctype html>
 
   <div class="box">
     <details open>
-      <summary>Trivy SBOM</summary>
+      <summary>SBOM</summary>
       <div>SBOM file: <code>{{ .Trivy.SBOMPath }}</code></div>
       <div>Status:
         {{ if .Trivy.OK }}<span class="pill ok">OK</span>{{ else }}<span class="pill bad">FAILED</span>{{ end }}
       </div>
+      {{ if .Trivy.Stdout }}<div class="muted">{{ .Trivy.Stdout }}</div>{{ end }}
       {{ if .Trivy.Stderr }}
         <details>
           <summary>Trivy stderr</summary>
@@ -252,6 +303,26 @@ const htmlTemplate = `<!doctype html>
     </details>
   </div>
 
+  {{ with .Image }}
+  <div class="box">
+    <details open>
+      <summary>Container Image</summary>
+      <div>Image: <code>{{ if .Name }}{{ .Name }}{{ else }}{{ .ID }}{{ end }}</code></div>
+      <div>ID: <code>{{ .ID }}</code></div>
+      <div>Platform: <code>{{ .OS }}/{{ .Architecture }}</code>{{ if .Created }} · created <code>{{ .Created }}</code>{{ end }}</div>
+      <div>Base image: {{ if or .BaseName .BaseDigest }}<code>{{ .BaseName }}{{ if .BaseDigest }}@{{ .BaseDigest }}{{ end }}</code>{{ else }}<span class="muted">not recorded (no org.opencontainers.image.base.* annotation)</span>{{ end }}</div>
+
+      <h3>Layers</h3>
+      <table>
+        <tr><th>#</th><th>Diff ID</th><th>Created by</th><th>Files</th><th>Whiteouts</th></tr>
+        {{ range $i, $l := .Layers }}
+          <tr><td>{{ $i }}</td><td><code>{{ if $l.DiffID }}{{ $l.DiffID }}{{ else }}{{ $l.Digest }}{{ end }}</code></td><td><code>{{ $l.CreatedBy }}</code></td><td>{{ $l.Files }}</td><td>{{ $l.Removed }}</td></tr>
+        {{ end }}
+      </table>
+    </details>
+  </div>
+  {{ end }}
+
   <div class="box">
     <details open>
       <summary>Project Git</summary>
@@ -289,52 +360,79 @@ const htmlTemplate = `<!doctype html>
         </div>
       </div>
 
-      <h3>Go modules</h3>
-      {{ if .Dependencies.GoModules }}
+      {{ $subprojects := .Subprojects }}
+      {{ if gt (len $subprojects) 1 }}
+      <h3>Subprojects</h3>
       <table>
-        <tr><th>Module</th><th>Version</th><th>Replace</th></tr>
-        {{ range .Dependencies.GoModules }}
-          <tr><td><code>{{ .Path }}</code></td><td><code>{{ .Version }}</code></td><td><code>{{ .Replace }}</code></td></tr>
+        <tr><th>Subproject</th><th>Dependencies</th><th>By ecosystem</th></tr>
+        {{ range $subprojects }}
+          <tr><td>{{ if .Path }}<code>{{ .Path }}</code>{{ else }}<span class="muted">root</span>{{ end }}</td><td>{{ .Total }}</td><td>{{ range $i, $c := .Counts }}{{ if $i }}, {{ end }}{{ $c.Ecosystem }} {{ $c.Count }}{{ end }}</td></tr>
         {{ end }}
       </table>
-      {{ else }}
-        <div class="muted">No go.mod or unable to list modules.</div>
       {{ end }}
 
-      <h3>NPM</h3>
-      {{ if .Dependencies.NpmPackages }}
-        <table>
-          <tr><th>Name</th><th>Version</th><th>Source</th></tr>
-          {{ range .Dependencies.NpmPackages }}
-            <tr><td><code>{{ .Name }}</code></td><td><code>{{ .Version }}</code></td><td><code>{{ .Source }}</code></td></tr>
-          {{ end }}
-        </table>
-      {{ else }}
-        <div class="muted">No npm lockfile detected (or not parsed).</div>
+      {{ $scopes := .Scopes }}
+      {{ if gt (len $scopes) 1 }}
+      <h3>Scopes</h3>
+      <table>
+        <tr><th>Scope</th><th>Dependencies</th><th>By ecosystem</th></tr>
+        {{ range $scopes }}
+          <tr><td>{{ .Scope }}</td><td>{{ .Total }}</td><td>{{ range $i, $c := .Counts }}{{ if $i }}, {{ end }}{{ $c.Ecosystem }} {{ $c.Count }}{{ end }}</td></tr>
+        {{ end }}
+      </table>
       {{ end }}
 
-      <h3>Python</h3>
-      {{ if .Dependencies.PythonReqs }}
-        <table>
-          <tr><th>Name</th><th>Version</th><th>Source</th></tr>
-          {{ range .Dependencies.PythonReqs }}
-            <tr><td><code>{{ .Name }}</code></td><td><code>{{ .Version }}</code></td><td><code>{{ .Source }}</code></td></tr>
-          {{ end }}
-        </table>
-      {{ else }}
-        <div class="muted">No python dependency files detected (or not parsed).</div>
+      {{ if .Dependencies.GoBinaries }}
+      <h3>Go binaries</h3>
+      <table>
+        <tr><th>Binary</th><th>Main module</th><th>Go</th><th>Platform</th><th>CGO</th><th>VCS revision</th></tr>
+        {{ range .Dependencies.GoBinaries }}
+          <tr><td><code>{{ .File }}</code></td><td><code>{{ .Main.Path }}</code> <code>{{ .Main.Version }}</code></td><td>{{ .GoVersion }}</td><td>{{ .Setting "GOOS" }}/{{ .Setting "GOARCH" }}</td><td>{{ .Setting "CGO_ENABLED" }}</td><td><code>{{ .Setting "vcs.revision" }}</code>{{ if eq (.Setting "vcs.modified") "true" }} <span class="muted">(modified)</span>{{ end }}</td></tr>
+        {{ end }}
+      </table>
+      {{ end }}
+
+      {{ with .Dependencies.Packages.GoBuild }}
+      {{ if .Mains }}
+      <h3>Go main modules</h3>
+      <table>
+        <tr><th>Module</th><th>Version</th><th>Go</th><th>Subproject</th></tr>
+        {{ range .Mains }}
+          <tr><td><code>{{ .Path }}</code></td><td><code>{{ .Version }}</code></td><td>{{ .GoVersion }}{{ if .Toolchain }} <span class="muted">({{ .Toolchain }})</span>{{ end }}</td><td>{{ if .Subproject }}<code>{{ .Subproject }}</code>{{ else }}<span class="muted">root</span>{{ end }}</td></tr>
+        {{ end }}
+      </table>
       {{ end }}
 
-      <h3>Maven</h3>
-      {{ if .Dependencies.MavenDeps }}
+      {{ range .Workspaces }}
+      <h3>Go workspace {{ if .Dir }}<code>{{ .Dir }}</code>{{ else }}<span class="muted">root</span>{{ end }}</h3>
+      <div class="muted">
+        Modules: {{ range $i, $m := .Modules }}{{ if $i }}, {{ end }}<code>{{ $m }}</code>{{ end }}
+        {{ if .GoVersion }} · go {{ .GoVersion }}{{ end }}{{ if .Toolchain }} · {{ .Toolchain }}{{ end }}
+        {{ range .Replaces }}<br>replace <code>{{ . }}</code>{{ end }}
+      </div>
+      <table>
+        <tr><th>Module</th><th>Version</th><th>Replace</th></tr>
+        {{ range .Merged }}
+          <tr><td><code>{{ .Path }}</code>{{ if .Main }} <span class="muted">(workspace module)</span>{{ else if .Indirect }} <span class="muted">(indirect)</span>{{ end }}</td><td><code>{{ .Version }}</code></td><td><code>{{ .Replace }}</code></td></tr>
+        {{ end }}
+      </table>
+      {{ end }}
+      {{ end }}
+
+      {{ range .Dependencies.Packages }}
+      {{ if or .Packages .Ecosystem.Empty }}
+      <h3>{{ .Ecosystem.Name }}</h3>
+      {{ if .Packages }}
         <table>
-          <tr><th>Name</th><th>Version</th><th>Source</th></tr>
-          {{ range .Dependencies.MavenDeps }}
-            <tr><td><code>{{ .Name }}</code></td><td><code>{{ .Version }}</code></td><td><code>{{ .Source }}</code></td></tr>
+          <tr><th>Name</th><th>Version</th><th>Scope</th><th>Details</th><th>Source</th><th>Subproject</th></tr>
+          {{ range .Packages }}
+            <tr><td><code>{{ .Name }}</code></td><td><code>{{ .Version }}</code>{{ if .Mismatch }} <span class="muted">⚠ {{ .Mismatch }}</span>{{ end }}{{ if .Unpinned }} <span class="muted">⚠ not pinned: {{ .Unpinned }}</span>{{ end }}{{ range .VersionConflicts }} <span class="muted">⚠ {{ . }}</span>{{ end }}</td><td>{{ .Scope }}</td><td>{{ pkgDetails . }}{{ if .Location }} <span class="muted">installed: <code>{{ .Location }}</code></span>{{ end }}</td><td>{{ if .SeenIn }}{{ range $i, $s := .SeenIn }}{{ if $i }}, {{ end }}<code>{{ $s }}</code>{{ end }}{{ else }}<code>{{ .Source }}</code>{{ end }}</td><td>{{ if .Subproject }}<code>{{ .Subproject }}</code>{{ else }}<span class="muted">root</span>{{ end }}</td></tr>
           {{ end }}
         </table>
       {{ else }}
-        <div class="muted">No pom.xml detected (or not parsed).</div>
+        <div class="muted">{{ .Ecosystem.Empty }}</div>
+      {{ end }}
+      {{ end }}
       {{ end }}
     </details>
   </div>
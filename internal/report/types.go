@@ -1,10 +1,12 @@
 package report
 
 import (
+	"sort"
 	"time"
 
 	"sbom-report/internal/deps"
 	"sbom-report/internal/git"
+	"sbom-report/internal/image"
 	"sbom-report/internal/repo"
 	"sbom-report/internal/sbom"
 )
@@ -15,6 +17,7 @@ type Report struct {
 
 	Trivy sbom.TrivyResult
 	SBOM  sbom.Summary
+	Image *image.Image // set when a container image was scanned
 
 	Project struct {
 		GitDetected bool
@@ -23,12 +26,99 @@ type Report struct {
 	}
 
 	Dependencies struct {
-		GoModules   []deps.GoModule
-		NpmPackages []deps.PackageRef
-		PythonReqs  []deps.PackageRef
-		MavenDeps   []deps.PackageRef
-		OtherNotes  []string
+		GoBinaries []deps.GoBinary // build info of scanned Go executables
+		Packages   deps.Collection // everything found by the registered discoverers, per ecosystem
+		OtherNotes []string
 	}
 
 	Repos []repo.Assessment
 }
+
+// SubprojectSummary counts the dependencies found in one subproject.
+type SubprojectSummary struct {
+	Path   string // "" for the root project
+	Counts []EcosystemCount
+	Total  int
+}
+
+type EcosystemCount struct {
+	Ecosystem string
+	Count     int
+}
+
+// Subprojects groups the discovered dependencies by the subproject they were
+// found in, root first and the rest by path.
+func (r *Report) Subprojects() []SubprojectSummary {
+	index := make(map[string]*SubprojectSummary)
+	count := func(path, ecosystem string) {
+		s, ok := index[path]
+		if !ok {
+			s = &SubprojectSummary{Path: path}
+			index[path] = s
+		}
+		s.Total++
+		for i := range s.Counts {
+			if s.Counts[i].Ecosystem == ecosystem {
+				s.Counts[i].Count++
+				return
+			}
+		}
+		s.Counts = append(s.Counts, EcosystemCount{Ecosystem: ecosystem, Count: 1})
+	}
+	for _, e := range r.Dependencies.Packages {
+		for _, p := range e.Packages {
+			count(p.Subproject, e.Ecosystem.Name)
+		}
+	}
+
+	out := make([]SubprojectSummary, 0, len(index))
+	for _, s := range index {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// ScopeSummary counts the dependencies of one scope (see deps.Scopes).
+type ScopeSummary struct {
+	Scope  string
+	Counts []EcosystemCount
+	Total  int
+}
+
+// Scopes groups the discovered dependencies by scope, in the order of
+// deps.Scopes; packages without a scope count as runtime.
+func (r *Report) Scopes() []ScopeSummary {
+	index := make(map[string]*ScopeSummary)
+	count := func(scope, ecosystem string) {
+		if scope == "" {
+			scope = deps.ScopeRuntime
+		}
+		s, ok := index[scope]
+		if !ok {
+			s = &ScopeSummary{Scope: scope}
+			index[scope] = s
+		}
+		s.Total++
+		for i := range s.Counts {
+			if s.Counts[i].Ecosystem == ecosystem {
+				s.Counts[i].Count++
+				return
+			}
+		}
+		s.Counts = append(s.Counts, EcosystemCount{Ecosystem: ecosystem, Count: 1})
+	}
+	for _, e := range r.Dependencies.Packages {
+		for _, p := range e.Packages {
+			count(p.Scope, e.Ecosystem.Name)
+		}
+	}
+
+	var out []ScopeSummary
+	for _, scope := range deps.Scopes {
+		if s, ok := index[scope]; ok {
+			out = append(out, *s)
+		}
+	}
+	return out
+}
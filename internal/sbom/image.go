@@ -0,0 +1,220 @@
+package sbom
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"sbom-report/internal/deps"
+	"sbom-report/internal/image"
+)
+
+// WriteImageBOM writes a CycloneDX JSON SBOM for an unpacked container
+// image. The image is the metadata component, with its layers as
+// properties and its base image as pedigree ancestor; every package
+// records each place it was found and the layer that added it.
+func WriteImageBOM(outputPath string, img *image.Image, pkgs deps.Collection) TrivyResult {
+	bom := cdx.NewBOM()
+	bom.SerialNumber = newSerialNumber()
+
+	name := img.Name
+	if name == "" {
+		name = img.ID
+	}
+	props := []cdx.Property{
+		{Name: "sbom-report:image:id", Value: img.ID},
+		{Name: "sbom-report:image:platform", Value: img.OS + "/" + img.Architecture},
+	}
+	if img.Created != "" {
+		props = append(props, cdx.Property{Name: "sbom-report:image:created", Value: img.Created})
+	}
+	for i, l := range img.Layers {
+		props = append(props, cdx.Property{
+			Name:  "sbom-report:layer:" + strconv.Itoa(i),
+			Value: strings.TrimSpace(layerID(l) + " " + l.CreatedBy),
+		})
+	}
+	root := cdx.Component{
+		BOMRef:     "image:" + img.ID,
+		Type:       cdx.ComponentTypeContainer,
+		Name:       name,
+		Version:    img.ID,
+		Properties: &props,
+	}
+	if img.BaseName != "" || img.BaseDigest != "" {
+		root.Pedigree = &cdx.Pedigree{Ancestors: &[]cdx.Component{{
+			Type:    cdx.ComponentTypeContainer,
+			Name:    img.BaseName,
+			Version: img.BaseDigest,
+		}}}
+	}
+	bom.Metadata = &cdx.Metadata{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Tools: &cdx.ToolsChoice{Components: &[]cdx.Component{{
+			Type: cdx.ComponentTypeApplication,
+			Name: "sbom-report",
+		}}},
+		Component: &root,
+	}
+
+	var components []cdx.Component
+	var refs []string
+	added := make(map[string]int)
+	add := func(c cdx.Component, file string) {
+		var where []cdx.Property
+		if file != "" {
+			where = append(where, cdx.Property{Name: "sbom-report:location", Value: file})
+			if l, ok := img.LayerOf(file); ok {
+				where = append(where, cdx.Property{Name: "sbom-report:layer", Value: layerID(l)})
+			}
+		}
+		if i, ok := added[c.BOMRef]; ok {
+			// the same package in another place, such as a module linked
+			// into several binaries
+			var props []cdx.Property
+			if components[i].Properties != nil {
+				props = *components[i].Properties
+			}
+			for _, p := range where {
+				if !containsProperty(props, p) {
+					props = append(props, p)
+				}
+			}
+			if len(props) > 0 {
+				components[i].Properties = &props
+			}
+			return
+		}
+		added[c.BOMRef] = len(components)
+
+		var props []cdx.Property
+		if c.Properties != nil {
+			props = *c.Properties
+		}
+		props = append(props, where...)
+		if len(props) > 0 {
+			c.Properties = &props
+		}
+		components = append(components, c)
+		refs = append(refs, c.BOMRef)
+	}
+
+	if rel := deps.ReadOSRelease(img.RootFS); rel.ID != "" {
+		add(cdx.Component{
+			BOMRef:      "os:" + rel.ID + "@" + rel.VersionID,
+			Type:        cdx.ComponentTypeOS,
+			Name:        rel.ID,
+			Version:     rel.VersionID,
+			Description: rel.PrettyName,
+		}, "etc/os-release")
+	}
+	gb := pkgs.GoBuild()
+	for _, m := range gb.Mains {
+		c := goModuleComponent(m)
+		c.Type = cdx.ComponentTypeApplication
+		add(c, m.Subproject) // the binary built from it, or its go.mod directory
+	}
+	for _, e := range pkgs {
+		for _, p := range e.Packages {
+			add(refComponent(gb, p), packageFile(p))
+		}
+	}
+	bom.Components = &components
+	bom.Dependencies = &[]cdx.Dependency{{Ref: root.BOMRef, Dependencies: &refs}}
+
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return TrivyResult{SBOMPath: outputPath, Stderr: err.Error()}
+	}
+	defer f.Close()
+	if err := cdx.NewBOMEncoder(f, cdx.BOMFileFormatJSON).SetPretty(true).Encode(bom); err != nil {
+		return TrivyResult{SBOMPath: outputPath, Stderr: err.Error()}
+	}
+	return TrivyResult{SBOMPath: outputPath, Stdout: fmt.Sprintf("%d layers, %d components", len(img.Layers), len(components)), OK: true}
+}
+
+// packageComponent describes a discovered package: its package URL (or
+// "ecosystem:name@version" as reference when it has none), lockfile hash,
+// licenses, and the source package of distro packages.
+func packageComponent(p deps.PackageRef) cdx.Component {
+	purl := p.PURL()
+	ref := purl
+	if ref == "" {
+		ref = p.Ecosystem + ":" + p.Name + "@" + p.Version
+	}
+	c := cdx.Component{
+		BOMRef:     ref,
+		Type:       cdx.ComponentTypeLibrary,
+		Name:       p.Name,
+		Version:    p.Version,
+		PackageURL: purl,
+	}
+	if h, ok := integrityHash(p); ok {
+		c.Hashes = &[]cdx.Hash{h}
+	}
+	if len(p.Licenses) > 0 {
+		var licenses cdx.Licenses
+		for _, l := range p.Licenses {
+			licenses = append(licenses, cdx.LicenseChoice{License: &cdx.License{Name: l}})
+		}
+		c.Licenses = &licenses
+	}
+	var props []cdx.Property
+	if yarnBerryChecksum(p) {
+		props = append(props, cdx.Property{Name: "sbom-report:yarn:checksum", Value: p.Integrity})
+	}
+	if p.OS != nil && p.OS.SourceName != "" {
+		props = append(props,
+			cdx.Property{Name: "sbom-report:os:source", Value: p.OS.SourceName},
+			cdx.Property{Name: "sbom-report:os:source-version", Value: p.OS.SourceVersion},
+		)
+	}
+	if len(props) > 0 {
+		c.Properties = &props
+	}
+	return c
+}
+
+// refComponent is packageComponent, except that a Go module is described
+// from its GoModule: as the replacement that is built in, with its go.sum
+// hash (see goModuleComponent).
+func refComponent(gb *deps.GoBuild, p deps.PackageRef) cdx.Component {
+	if m, ok := gb.Module(p); ok {
+		return goModuleComponent(m)
+	}
+	return packageComponent(p)
+}
+
+// packageFile returns the path, relative to the scanned root, of the file a
+// package was found in: its install location, or else its manifest.
+func packageFile(p deps.PackageRef) string {
+	if p.Location != "" {
+		loc, _, _ := strings.Cut(p.Location, "!/") // the archive itself
+		return loc
+	}
+	if p.Source == "" || p.Source == deps.SBOMSource {
+		return ""
+	}
+	return path.Join(p.Subproject, p.Source)
+}
+
+func layerID(l image.Layer) string {
+	if l.DiffID != "" {
+		return l.DiffID
+	}
+	return l.Digest
+}
+
+// containsProperty reports whether props has p with the same value.
+func containsProperty(props []cdx.Property, p cdx.Property) bool {
+	for _, have := range props {
+		if have == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,497 @@
+package sbom
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"sbom-report/internal/deps"
+)
+
+// WriteProjectBOM writes a CycloneDX JSON SBOM of a source tree from what
+// the dependency discoverers found. The project is the metadata component,
+// named after its root go.mod, package.json or pom.xml; every package is a
+// component with its package URL, lockfile hash, licenses and scope; and
+// the dependency graph follows the parents the discoverers record.
+// When trivy succeeded, its SBOM at outputPath is kept and completed
+// instead: components it lacks are added, those it has gain the hashes,
+// licenses and scope it left out, and the edges are merged. Otherwise the
+// SBOM stands in for Trivy's.
+func WriteProjectBOM(outputPath, dir string, pkgs deps.Collection, trivy TrivyResult) TrivyResult {
+	gb := pkgs.GoBuild()
+	root, fromManifest := projectComponent(dir, gb.Mains)
+	b := newProjectBOM(root)
+
+	// each Go main module (the root one is the project itself) is an
+	// application the packages of its subproject hang off
+	owners := make(map[string]string)
+	for _, m := range gb.Mains {
+		c := goModuleComponent(m)
+		if m.Subproject == "" && root.BOMRef == c.BOMRef {
+			continue
+		}
+		c.Type = cdx.ComponentTypeApplication
+		b.add(c, "", m.Subproject)
+		b.link(root.BOMRef, c.BOMRef)
+		owners[m.Subproject] = c.BOMRef
+	}
+
+	for _, e := range pkgs {
+		refs := make(map[string][]string) // subproject and name -> references
+		for _, p := range e.Packages {
+			key := p.Subproject + "\x00" + p.Name
+			ref := refComponent(gb, p).BOMRef
+			if !containsRef(refs[key], ref) {
+				refs[key] = append(refs[key], ref)
+			}
+		}
+		for _, p := range e.Packages {
+			c := refComponent(gb, p)
+			b.add(c, p.Scope, packageFile(p))
+			owner, ok := owners[p.Subproject]
+			if !ok {
+				owner = root.BOMRef
+			}
+			if p.Direct || len(p.Parents) == 0 {
+				b.link(owner, c.BOMRef)
+			}
+			for _, parent := range p.Parents {
+				parentRefs, ok := refs[p.Subproject+"\x00"+parent]
+				if !ok {
+					b.link(owner, c.BOMRef)
+				}
+				for _, ref := range parentRefs {
+					b.link(ref, c.BOMRef)
+				}
+			}
+		}
+	}
+
+	var bom *cdx.BOM
+	var summary string
+	if trivy.OK {
+		base, err := readBOM(outputPath)
+		if err != nil {
+			// not CycloneDX (--sbom-format), so leave Trivy's SBOM alone
+			trivy.Stderr = strings.TrimSpace(trivy.Stderr + "\n(not merging discovered dependencies: " + err.Error() + ")")
+			return trivy
+		}
+		added := mergeBOM(base, b, fromManifest)
+		bom, summary = base, fmt.Sprintf("merged %d discovered components into the Trivy SBOM", added)
+	} else {
+		bom, summary = b.bom(), fmt.Sprintf("%d components from discovered dependencies", len(b.components))
+	}
+
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return TrivyResult{SBOMPath: outputPath, Stdout: trivy.Stdout, Stderr: strings.TrimSpace(trivy.Stderr + "\n" + err.Error())}
+	}
+	defer f.Close()
+	if err := cdx.NewBOMEncoder(f, cdx.BOMFileFormatJSON).SetPretty(true).Encode(bom); err != nil {
+		return TrivyResult{SBOMPath: outputPath, Stdout: trivy.Stdout, Stderr: strings.TrimSpace(trivy.Stderr + "\n" + err.Error())}
+	}
+	return TrivyResult{SBOMPath: outputPath, Stdout: strings.TrimSpace(trivy.Stdout + "\n" + summary), Stderr: trivy.Stderr, OK: true}
+}
+
+// projectBOM collects the components and dependency edges of a project
+// SBOM, each component once.
+type projectBOM struct {
+	root       cdx.Component
+	components []cdx.Component
+	index      map[string]int    // reference -> index in components
+	scopes     map[string]string // reference -> widest deps scope
+	edges      map[string][]string
+	order      []string // references with edges, in the order first linked
+}
+
+func newProjectBOM(root cdx.Component) *projectBOM {
+	return &projectBOM{
+		root:   root,
+		index:  make(map[string]int),
+		scopes: make(map[string]string),
+		edges:  make(map[string][]string),
+	}
+}
+
+// add records a component with its deps scope and the file it was found in,
+// widening the scope if the component was already added.
+func (b *projectBOM) add(c cdx.Component, scope, file string) {
+	if scope != "" {
+		b.scopes[c.BOMRef] = deps.WiderScope(b.scopes[c.BOMRef], scope)
+	}
+	if _, ok := b.index[c.BOMRef]; ok {
+		return
+	}
+	if file != "" {
+		var props []cdx.Property
+		if c.Properties != nil {
+			props = *c.Properties
+		}
+		props = append(props, cdx.Property{Name: "sbom-report:location", Value: file})
+		c.Properties = &props
+	}
+	b.index[c.BOMRef] = len(b.components)
+	b.components = append(b.components, c)
+}
+
+func (b *projectBOM) link(from, to string) {
+	if from == to || containsRef(b.edges[from], to) {
+		return
+	}
+	if _, ok := b.edges[from]; !ok {
+		b.order = append(b.order, from)
+	}
+	b.edges[from] = append(b.edges[from], to)
+}
+
+// finish applies the collected scopes to the components.
+func (b *projectBOM) finish() {
+	for ref, scope := range b.scopes {
+		c := &b.components[b.index[ref]]
+		c.Scope = componentScope(scope)
+		props := []cdx.Property{{Name: "sbom-report:scope", Value: scope}}
+		if c.Properties != nil {
+			props = append(*c.Properties, props...)
+		}
+		c.Properties = &props
+	}
+}
+
+func (b *projectBOM) dependencies() []cdx.Dependency {
+	var out []cdx.Dependency
+	for _, ref := range b.order {
+		refs := b.edges[ref]
+		out = append(out, cdx.Dependency{Ref: ref, Dependencies: &refs})
+	}
+	return out
+}
+
+func (b *projectBOM) bom() *cdx.BOM {
+	b.finish()
+	bom := cdx.NewBOM()
+	bom.SerialNumber = newSerialNumber()
+	root := b.root
+	bom.Metadata = &cdx.Metadata{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Tools: &cdx.ToolsChoice{Components: &[]cdx.Component{{
+			Type: cdx.ComponentTypeApplication,
+			Name: "sbom-report",
+		}}},
+		Component: &root,
+	}
+	components := b.components
+	dependencies := b.dependencies()
+	bom.Components = &components
+	bom.Dependencies = &dependencies
+	return bom
+}
+
+// mergeBOM completes a Trivy SBOM with the collected components, matching
+// them by package URL, and returns how many were added. The project
+// component takes the name and version of the manifest when there is one.
+func mergeBOM(base *cdx.BOM, b *projectBOM, fromManifest bool) int {
+	b.finish()
+	refs := map[string]string{} // collected reference -> reference in base
+	if base.Metadata == nil {
+		base.Metadata = &cdx.Metadata{}
+	}
+	if base.Metadata.Tools == nil {
+		base.Metadata.Tools = &cdx.ToolsChoice{}
+	}
+	if tools := base.Metadata.Tools; tools.Tools != nil {
+		*tools.Tools = append(*tools.Tools, cdx.Tool{Name: "sbom-report"}) // spec 1.4 and older
+	} else {
+		if tools.Components == nil {
+			tools.Components = &[]cdx.Component{}
+		}
+		*tools.Components = append(*tools.Components, cdx.Component{
+			Type: cdx.ComponentTypeApplication,
+			Name: "sbom-report",
+		})
+	}
+	if root := base.Metadata.Component; root != nil {
+		if root.BOMRef == "" {
+			root.BOMRef = b.root.BOMRef
+		}
+		refs[b.root.BOMRef] = root.BOMRef
+		if fromManifest {
+			root.Name, root.Group, root.Version, root.PackageURL = b.root.Name, b.root.Group, b.root.Version, b.root.PackageURL
+		}
+	} else {
+		root := b.root
+		base.Metadata.Component = &root
+	}
+
+	if base.Components == nil {
+		base.Components = &[]cdx.Component{}
+	}
+	byPURL := make(map[string]int)
+	for i, c := range *base.Components {
+		if c.PackageURL != "" {
+			byPURL[purlKey(c.PackageURL)] = i
+		}
+	}
+	added := 0
+	for _, c := range b.components {
+		i, ok := byPURL[purlKey(c.PackageURL)]
+		if c.PackageURL == "" || !ok {
+			*base.Components = append(*base.Components, c)
+			refs[c.BOMRef] = c.BOMRef
+			added++
+			continue
+		}
+		have := &(*base.Components)[i]
+		if have.BOMRef == "" {
+			have.BOMRef = c.BOMRef
+		}
+		refs[c.BOMRef] = have.BOMRef
+		if have.Hashes == nil {
+			have.Hashes = c.Hashes
+		}
+		if have.Licenses == nil {
+			have.Licenses = c.Licenses
+		}
+		if have.Scope == "" {
+			have.Scope = c.Scope
+		}
+		if c.Properties != nil {
+			var props []cdx.Property
+			if have.Properties != nil {
+				props = *have.Properties
+			}
+			props = append(props, *c.Properties...)
+			have.Properties = &props
+		}
+	}
+
+	if base.Dependencies == nil {
+		base.Dependencies = &[]cdx.Dependency{}
+	}
+	byRef := make(map[string]int)
+	for i, d := range *base.Dependencies {
+		byRef[d.Ref] = i
+	}
+	for _, d := range b.dependencies() {
+		from := mappedRef(refs, d.Ref)
+		i, ok := byRef[from]
+		if !ok {
+			byRef[from] = len(*base.Dependencies)
+			*base.Dependencies = append(*base.Dependencies, cdx.Dependency{Ref: from, Dependencies: &[]string{}})
+			i = byRef[from]
+		}
+		have := &(*base.Dependencies)[i]
+		if have.Dependencies == nil {
+			have.Dependencies = &[]string{}
+		}
+		for _, to := range *d.Dependencies {
+			if to = mappedRef(refs, to); to != from && !containsRef(*have.Dependencies, to) {
+				*have.Dependencies = append(*have.Dependencies, to)
+			}
+		}
+	}
+	return added
+}
+
+func mappedRef(refs map[string]string, ref string) string {
+	if r, ok := refs[ref]; ok {
+		return r
+	}
+	return ref
+}
+
+// purlKey compares package URLs without qualifiers and subpath, which
+// Trivy and the discoverers fill in differently.
+func purlKey(purl string) string {
+	purl, _, _ = strings.Cut(purl, "#")
+	purl, _, _ = strings.Cut(purl, "?")
+	return strings.ToLower(purl)
+}
+
+func readBOM(path string) (*cdx.BOM, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var bom cdx.BOM
+	if err := cdx.NewBOMDecoder(f, cdx.BOMFileFormatJSON).Decode(&bom); err != nil {
+		return nil, err
+	}
+	if bom.BOMFormat != cdx.BOMFormat {
+		return nil, fmt.Errorf("%s is not a CycloneDX SBOM", path)
+	}
+	return &bom, nil
+}
+
+// projectComponent describes the scanned project after its root go.mod,
+// package.json or pom.xml, or else after its directory; it reports whether
+// a manifest named it.
+func projectComponent(dir string, goMains []deps.GoModule) (cdx.Component, bool) {
+	for _, m := range goMains {
+		if m.Subproject == "" && m.Path != "" {
+			c := goModuleComponent(m)
+			c.Type = cdx.ComponentTypeApplication
+			return c, true
+		}
+	}
+
+	var ref deps.PackageRef
+	if b, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		var pj struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}
+		if json.Unmarshal(b, &pj) == nil && pj.Name != "" {
+			ref = deps.PackageRef{Ecosystem: "npm", Name: pj.Name, Version: pj.Version}
+		}
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "pom.xml")); ref.Name == "" && err == nil {
+		var pom struct {
+			GroupID    string `xml:"groupId"`
+			ArtifactID string `xml:"artifactId"`
+			Version    string `xml:"version"`
+			Parent     struct {
+				GroupID string `xml:"groupId"`
+				Version string `xml:"version"`
+			} `xml:"parent"`
+		}
+		if xml.Unmarshal(b, &pom) == nil && pom.ArtifactID != "" {
+			group := firstNonEmpty(pom.GroupID, pom.Parent.GroupID)
+			version := firstNonEmpty(pom.Version, pom.Parent.Version)
+			if strings.Contains(version, "${") {
+				version = "" // ${revision} and other CI-friendly versions
+			}
+			ref = deps.PackageRef{Ecosystem: "maven", Name: group + ":" + pom.ArtifactID, Version: version}
+		}
+	}
+	if ref.Name != "" {
+		c := cdx.Component{
+			Type:       cdx.ComponentTypeApplication,
+			Name:       ref.Name,
+			Version:    ref.Version,
+			PackageURL: ref.PURL(),
+		}
+		c.BOMRef = c.PackageURL
+		if c.BOMRef == "" {
+			c.BOMRef = "project:" + ref.Name
+		}
+		return c, true
+	}
+
+	name := filepath.Base(dir)
+	return cdx.Component{BOMRef: "project:" + name, Type: cdx.ComponentTypeApplication, Name: name}, false
+}
+
+// componentScope maps a deps scope to a CycloneDX one: runtime
+// dependencies are required, optional ones optional, and build, dev and
+// test dependencies are not part of what is shipped.
+func componentScope(scope string) cdx.Scope {
+	switch scope {
+	case deps.ScopeRuntime:
+		return cdx.ScopeRequired
+	case deps.ScopeOptional:
+		return cdx.ScopeOptional
+	case "":
+		return ""
+	}
+	return cdx.ScopeExcluded
+}
+
+var hexRe = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+
+// integrityHash converts the hash a lockfile records for a package: npm,
+// pnpm and Yarn subresource integrity ("sha512-<base64>"), "algo:hex" and
+// "algo=hex" (pip, uv, Poetry, Bundler) and the bare hex or base64 of Cargo,
+// Composer and NuGet. Yarn Berry checksums are not hashes of the package;
+// see yarnBerryChecksum.
+func integrityHash(p deps.PackageRef) (cdx.Hash, bool) {
+	sum := p.Integrity
+	if sum == "" {
+		return cdx.Hash{}, false
+	}
+	if algo, b64, ok := strings.Cut(sum, "-"); ok {
+		if raw, err := base64.StdEncoding.DecodeString(b64); err == nil {
+			return hashOf(algo, hex.EncodeToString(raw))
+		}
+	}
+	for _, sep := range []string{":", "="} {
+		if algo, value, ok := strings.Cut(sum, sep); ok && hexRe.MatchString(value) {
+			return hashOf(algo, value)
+		}
+	}
+	switch {
+	case hexRe.MatchString(sum) && len(sum) == 64:
+		return hashOf("sha256", sum)
+	case hexRe.MatchString(sum) && len(sum) == 40:
+		return hashOf("sha1", sum)
+	case p.Ecosystem == "nuget":
+		if raw, err := base64.StdEncoding.DecodeString(sum); err == nil && len(raw) == 64 {
+			return hashOf("sha512", hex.EncodeToString(raw))
+		}
+	}
+	return cdx.Hash{}, false
+}
+
+// yarnBerryChecksum reports whether a package's Integrity is a Yarn Berry
+// checksum ("<cache key>/<hex>", or bare hex before Yarn 4). It hashes the
+// zip Yarn writes to its own cache rather than the registry tarball, so it
+// is recorded as a property, not as a hash a download could be checked
+// against.
+func yarnBerryChecksum(p deps.PackageRef) bool {
+	if p.Ecosystem != "npm" || path.Base(p.Source) != "yarn.lock" || p.Integrity == "" {
+		return false
+	}
+	_, value, _ := strings.Cut(p.Integrity, "/")
+	if value == "" {
+		value = p.Integrity
+	}
+	return hexRe.MatchString(value)
+}
+
+// hashAlgos maps lockfile algorithm names to CycloneDX ones and the length
+// of their hex digests.
+var hashAlgos = map[string]struct {
+	algo cdx.HashAlgorithm
+	len  int
+}{
+	"md5":    {cdx.HashAlgoMD5, 32},
+	"sha1":   {cdx.HashAlgoSHA1, 40},
+	"sha256": {cdx.HashAlgoSHA256, 64},
+	"sha384": {cdx.HashAlgoSHA384, 96},
+	"sha512": {cdx.HashAlgoSHA512, 128},
+}
+
+func hashOf(algo, value string) (cdx.Hash, bool) {
+	a, ok := hashAlgos[strings.ToLower(strings.ReplaceAll(algo, "-", ""))]
+	if !ok || len(value) != a.len {
+		return cdx.Hash{}, false
+	}
+	return cdx.Hash{Algorithm: a.algo, Value: strings.ToLower(value)}, true
+}
+
+func containsRef(refs []string, ref string) bool {
+	for _, r := range refs {
+		if r == ref {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -160,7 +160,7 @@ func ParseCycloneDX(path string) (*Summary, error) {
 
 	summary := &Summary{
 		Format:         "CycloneDX JSON",
-		SpecVersion:    string(bom.SpecVersion),
+		SpecVersion:    bom.SpecVersion.String(),
 		SerialNumber:   bom.SerialNumber,
 		ComponentTypes: map[string]int{},
 		Namespaces:     map[string]int{},
@@ -170,6 +170,9 @@ func ParseCycloneDX(path string) (*Summary, error) {
 		if bom.Metadata.Tools != nil && bom.Metadata.Tools.Tools != nil && len(*bom.Metadata.Tools.Tools) > 0 {
 			t := (*bom.Metadata.Tools.Tools)[0]
 			summary.MetadataTool = strings.TrimSpace(t.Name + " " + t.Version)
+		} else if bom.Metadata.Tools != nil && bom.Metadata.Tools.Components != nil && len(*bom.Metadata.Tools.Components) > 0 {
+			t := (*bom.Metadata.Tools.Components)[0]
+			summary.MetadataTool = strings.TrimSpace(t.Name + " " + t.Version)
 		}
 		if bom.Metadata.Timestamp != "" {
 			summary.MetadataTime = bom.Metadata.Timestamp
@@ -251,26 +254,29 @@ func ExtractPackagesFromSBOM(sbomPath string) (npm []deps.PackageRef, python []d
 
 		if strings.HasPrefix(purl, "pkg:npm/") {
 			name := c.Name
-			key := "npm:" + name
+			if c.Group != "" && !strings.HasPrefix(name, "@") {
+				name = c.Group + "/" + name // scoped packages
+			}
+			key := "npm:" + name + "@" + c.Version
 			if !seen[key] {
 				seen[key] = true
 				npm = append(npm, deps.PackageRef{
 					Ecosystem: "npm",
 					Name:      name,
 					Version:   c.Version,
-					Source:    "SBOM",
+					Source:    deps.SBOMSource,
 				})
 			}
 		} else if strings.HasPrefix(purl, "pkg:pypi/") {
 			name := c.Name
-			key := "pypi:" + name
+			key := "pypi:" + name + "@" + c.Version
 			if !seen[key] {
 				seen[key] = true
 				python = append(python, deps.PackageRef{
 					Ecosystem: "python",
 					Name:      name,
 					Version:   c.Version,
-					Source:    "SBOM",
+					Source:    deps.SBOMSource,
 				})
 			}
 		}
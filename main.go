@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"sbom-report/internal/config"
 	"sbom-report/internal/deps"
 	"sbom-report/internal/git"
 	"sbom-report/internal/graph"
+	"sbom-report/internal/image"
 	"sbom-report/internal/repo"
 	"sbom-report/internal/report"
 	"sbom-report/internal/sbom"
@@ -19,13 +22,24 @@ import (
 func main() {
 	var cfg config.Config
 	flag.StringVar(&cfg.BaseDir, "dir", ".", "Project base directory")
+	flag.StringVar(&cfg.BinaryPath, "binary", "", "Go executable (or directory of executables) to build the SBOM from its embedded build info, instead of scanning -dir")
+	flag.StringVar(&cfg.ImagePath, "image", "", "Container image to scan instead of -dir: a docker-archive (docker save) or OCI image-layout tarball, or an OCI layout directory")
 	flag.StringVar(&cfg.OutDir, "out", "out", "Output directory")
 	flag.StringVar(&cfg.TrivyPath, "trivy", "trivy", "Path to trivy executable")
 	flag.StringVar(&cfg.GitHubToken, "github-token", os.Getenv("GITHUB_TOKEN"), "GitHub token (or set GITHUB_TOKEN)")
 	flag.BoolVar(&cfg.EnableGeoGuess, "geo-guess", false, "Try to guess country from owner location string (very naive)")
 	flag.DurationVar(&cfg.RequestTimeout, "http-timeout", 12*time.Second, "HTTP timeout")
 	flag.StringVar(&cfg.TrivyFormat, "sbom-format", "cyclonedx", "Trivy SBOM format (cyclonedx recommended)")
+	flag.StringVar(&cfg.SBOMSource, "sbom-source", "merged", "How to build the SBOM of a source tree: trivy, native (from the discovered dependencies, without Trivy) or merged (Trivy's, completed with the discovered dependencies)")
+	flag.StringVar(&cfg.MavenRepo, "maven-repo", deps.DefaultMavenRepo(), "Local Maven repository for resolving transitive dependencies (empty to disable)")
+	var include, exclude, scopes string
+	flag.StringVar(&include, "include", "", "Comma-separated directory globs to discover manifests in, e.g. \"services/**\" (default: the whole tree)")
+	flag.StringVar(&exclude, "exclude", "", "Comma-separated directory globs to skip when discovering manifests, e.g. \"examples,**/testdata\"")
+	flag.StringVar(&scopes, "scope", "", "Comma-separated dependency scopes to report: "+strings.Join(deps.Scopes, ", ")+" (default: all)")
 	flag.Parse()
+	cfg.Include = splitList(include)
+	cfg.Exclude = splitList(exclude)
+	cfg.Scopes = splitList(scopes)
 
 	cfg.Now = time.Now()
 	cfg.UserAgent = "sbom-report/1.0"
@@ -47,7 +61,46 @@ func main() {
 	}
 }
 
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
 func run(cfg *config.Config) error {
+	for _, s := range cfg.Scopes {
+		if !slices.Contains(deps.Scopes, s) {
+			return fmt.Errorf("unknown scope %q (want %s)", s, strings.Join(deps.Scopes, ", "))
+		}
+	}
+	switch cfg.SBOMSource {
+	case "", "trivy", "native", "merged":
+	default:
+		return fmt.Errorf("unknown SBOM source %q (want trivy, native or merged)", cfg.SBOMSource)
+	}
+	if cfg.BinaryPath != "" {
+		cfg.BaseDir = cfg.BinaryPath
+	}
+	var img *image.Image
+	if cfg.ImagePath != "" {
+		// Unpack the layers and scan the resulting filesystem like a project
+		tmp, err := os.MkdirTemp("", "sbom-report-image-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		fmt.Println("Unpacking image", cfg.ImagePath)
+		if img, err = image.Load(cfg.ImagePath, tmp); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Applied %d image layers\n", len(img.Layers))
+		cfg.BaseDir = img.RootFS
+	}
 	baseDir, err := filepath.Abs(cfg.BaseDir)
 	if err != nil {
 		return err
@@ -61,25 +114,32 @@ func run(cfg *config.Config) error {
 	rep := &report.Report{
 		GeneratedAt: cfg.Now,
 		BaseDir:     cfg.BaseDir,
+		Image:       img,
+	}
+	if img != nil {
+		rep.BaseDir = cfg.ImagePath
 	}
 
-	// Run trivy SBOM
+	// Run trivy SBOM, or build it from the build info of Go binaries
 	sbomPath := filepath.Join(cfg.OutDir, cfg.TrivySBOMName)
-	rep.Trivy = sbom.RunTrivy(cfg.TrivyPath, cfg.TrivyFormat, cfg.BaseDir, sbomPath)
-
-	// Parse SBOM (best-effort)
-	if rep.Trivy.OK {
-		summary, err := sbom.ParseCycloneDX(sbomPath)
-		if err != nil {
-			rep.SBOM.Errors = append(rep.SBOM.Errors, err.Error())
-		} else {
-			rep.SBOM = *summary
+	if cfg.BinaryPath != "" {
+		rep.Dependencies.GoBinaries = deps.ReadGoBinaries(cfg.BaseDir)
+		if len(rep.Dependencies.GoBinaries) == 0 {
+			return fmt.Errorf("no Go build info found in %s", cfg.BaseDir)
 		}
+		fmt.Printf("✓ Read build info from %d Go binaries\n", len(rep.Dependencies.GoBinaries))
+		rep.Trivy = sbom.WriteGoBinaryBOM(sbomPath, rep.Dependencies.GoBinaries)
+	} else if img == nil && cfg.SBOMSource != "native" {
+		// an image's SBOM is written once its filesystem has been scanned,
+		// and a native one once the dependencies have been discovered
+		rep.Trivy = sbom.RunTrivy(cfg.TrivyPath, cfg.TrivyFormat, cfg.BaseDir, sbomPath)
+	}
 
-		// Extract packages from SBOM components
+	// Extract packages from SBOM components (best-effort)
+	if rep.Trivy.OK {
 		npmPkgs, pythonPkgs := sbom.ExtractPackagesFromSBOM(sbomPath)
-		rep.Dependencies.NpmPackages = append(rep.Dependencies.NpmPackages, npmPkgs...)
-		rep.Dependencies.PythonReqs = append(rep.Dependencies.PythonReqs, pythonPkgs...)
+		rep.Dependencies.Packages.Add(npmPkgs...)
+		rep.Dependencies.Packages.Add(pythonPkgs...)
 	}
 
 	// Run vulnerability scan
@@ -116,55 +176,81 @@ func run(cfg *config.Config) error {
 	}
 	cfg.VulnMap = cfgVulnMap
 
-	// Discover project git info + remotes
-	rep.Project.GitDetected = git.IsGitRepo(cfg.BaseDir)
-	if rep.Project.GitDetected {
-		rep.Project.Remotes = git.GetRemotes(cfg.BaseDir)
-		rep.Project.LastCommit = git.GetLastCommit(cfg.BaseDir)
-	}
+	if cfg.BinaryPath != "" {
+		// Binaries carry no manifests or git metadata, only their build list
+		rep.Dependencies.Packages.AddGoModules(deps.GoBinaryModules(rep.Dependencies.GoBinaries))
+	} else {
+		// Discover project git info + remotes
+		rep.Project.GitDetected = img == nil && git.IsGitRepo(cfg.BaseDir)
+		if rep.Project.GitDetected {
+			rep.Project.Remotes = git.GetRemotes(cfg.BaseDir)
+			rep.Project.LastCommit = git.GetLastCommit(cfg.BaseDir)
+		}
 
-	// Discover package repository usage (best-effort)
-	rep.Dependencies.GoModules = deps.DiscoverGoModules(cfg.BaseDir)
-	rep.Dependencies.NpmPackages = append(rep.Dependencies.NpmPackages, deps.DiscoverNpm(cfg.BaseDir)...)
-	rep.Dependencies.PythonReqs = append(rep.Dependencies.PythonReqs, deps.DiscoverPythonReqs(cfg.BaseDir)...)
-	rep.Dependencies.MavenDeps = deps.DiscoverMaven(cfg.BaseDir)
+		// Discover package repository usage (best-effort)
+		discoverOpts := deps.Options{MavenRepo: cfg.MavenRepo, Include: cfg.Include, Exclude: cfg.Exclude}
+		rep.Dependencies.Packages.Discover(cfg.BaseDir, discoverOpts)
+	}
+	rep.Dependencies.Packages.Merge()
+	rep.Dependencies.Packages = rep.Dependencies.Packages.FilterScopes(cfg.Scopes)
+	if img == nil && cfg.BinaryPath == "" && cfg.SBOMSource != "trivy" {
+		// Complete Trivy's SBOM with the discovered dependencies, or stand in for it
+		rep.Trivy = sbom.WriteProjectBOM(sbomPath, cfg.BaseDir, rep.Dependencies.Packages, rep.Trivy)
+	}
+	if img == nil && rep.Trivy.OK {
+		// Parse SBOM (best-effort)
+		if summary, err := sbom.ParseCycloneDX(sbomPath); err != nil {
+			rep.SBOM.Errors = append(rep.SBOM.Errors, err.Error())
+		} else {
+			rep.SBOM = *summary
+		}
+	}
+	if img != nil {
+		// Go binaries installed in the image, then the image SBOM
+		rep.Dependencies.GoBinaries = deps.ReadGoBinaries(cfg.BaseDir)
+		rep.Dependencies.Packages.AddGoModules(deps.GoBinaryModules(rep.Dependencies.GoBinaries))
+		rep.Trivy = sbom.WriteImageBOM(sbomPath, img, rep.Dependencies.Packages)
+		if summary, err := sbom.ParseCycloneDX(sbomPath); err != nil {
+			rep.SBOM.Errors = append(rep.SBOM.Errors, err.Error())
+		} else {
+			rep.SBOM = *summary
+		}
+	}
 
 	// Assess remote repos (focus on GitHub out-of-box)
 	// Start with git remotes
 	rep.Repos = repo.AssessRemotes(cfg, rep.Project.Remotes, rep.Project.LastCommit)
 
-	// Extract repository info from Go modules
-	goModuleRepos := repo.ExtractReposFromGoModules(rep.Dependencies.GoModules)
-	rep.Repos = append(rep.Repos, repo.AssessModuleRepos(cfg, goModuleRepos)...)
-
-	// Extract repository info from Python packages
-	pythonRepos := repo.ExtractReposFromPythonPackages(cfg, rep.Dependencies.PythonReqs)
-	fmt.Printf("✓ Resolved %d/%d Python packages to GitHub repos\n", len(pythonRepos), len(rep.Dependencies.PythonReqs))
-	rep.Repos = append(rep.Repos, repo.AssessModuleRepos(cfg, pythonRepos)...)
-
-	// Extract repository info from NPM packages
-	npmRepos := repo.ExtractReposFromNpmPackages(cfg, rep.Dependencies.NpmPackages)
-	fmt.Printf("✓ Resolved %d/%d NPM packages to GitHub repos\n", len(npmRepos), len(rep.Dependencies.NpmPackages))
-	rep.Repos = append(rep.Repos, repo.AssessModuleRepos(cfg, npmRepos)...)
+	// Extract repository info from packages of ecosystems with a registry lookup
+	for _, e := range rep.Dependencies.Packages {
+		remotes, ok := repo.ExtractReposFromPackages(cfg, e.Ecosystem.ID, e.Packages)
+		if !ok {
+			continue
+		}
+		fmt.Printf("✓ Resolved %d/%d %s packages to GitHub repos\n", len(remotes), len(e.Packages), e.Ecosystem.Name)
+		rep.Repos = append(rep.Repos, repo.AssessModuleRepos(cfg, remotes)...)
+	}
 
 	// Generate dependency graph SVG
 	graphPath := filepath.Join(cfg.OutDir, cfg.GraphSVGName)
 	projectName := filepath.Base(cfg.BaseDir)
+	if img != nil {
+		projectName = img.Name
+		if projectName == "" {
+			projectName = img.ID
+		}
+	}
 	fmt.Println("\nGenerating dependency graph...")
 	if err := graph.GenerateDependencyGraph(
 		graphPath,
 		projectName,
-		rep.Dependencies.GoModules,
-		rep.Dependencies.NpmPackages,
-		rep.Dependencies.PythonReqs,
-		rep.Dependencies.MavenDeps,
+		rep.Dependencies.Packages,
 		rep.Repos,
 	); err != nil {
 		fmt.Printf("Warning: failed to generate dependency graph: %v\n", err)
 	} else {
 		fmt.Printf("✓ Generated dependency graph with %d dependencies\n",
-			len(rep.Dependencies.GoModules)+len(rep.Dependencies.NpmPackages)+
-				len(rep.Dependencies.PythonReqs)+len(rep.Dependencies.MavenDeps))
+			rep.Dependencies.Packages.Len())
 	}
 
 	// Render HTML report